@@ -3,6 +3,8 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"github.com/JunNishimura/jsop/token"
 )
@@ -20,6 +22,23 @@ type IntegerLiteral struct {
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// BigIntegerLiteral is an integer literal which does not fit in int64.
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bil *BigIntegerLiteral) TokenLiteral() string { return bil.Token.Literal }
+func (bil *BigIntegerLiteral) String() string       { return bil.Token.Literal }
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -55,19 +74,24 @@ func (pa *PrefixAtom) String() string {
 type Array struct {
 	Token    token.Token
 	Elements []Expression
+	// Comments are the elements which are objects of only comments
+	Comments []*Comment
 }
 
 func (a *Array) TokenLiteral() string { return a.Token.Literal }
 func (a *Array) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("[")
+	elements := make([]string, len(a.Elements))
 	for i, el := range a.Elements {
-		if i > 0 {
-			out.WriteString(", ")
-		}
-		out.WriteString(el.String())
+		elements[i] = el.String()
 	}
+	elements = insertComments(elements, a.Comments, func(c *Comment) string {
+		return fmt.Sprintf("{%s}", c.String())
+	})
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
 	out.WriteString("]")
 
 	return out.String()
@@ -81,23 +105,32 @@ type Object interface {
 type KeyValueObject struct {
 	Token token.Token
 	KV    []*KeyValuePair
+	// Comments are the pairs of the comment key, which are not included in KV
+	Comments []*Comment
 }
 
 func (k *KeyValueObject) TokenLiteral() string { return k.Token.Literal }
 func (k *KeyValueObject) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("{")
+	pairs := make([]string, len(k.KV))
 	for i, kv := range k.KV {
-		if i > 0 {
-			out.WriteString(", ")
-		}
-		out.WriteString(kv.String())
+		pairs[i] = kv.String()
 	}
+	pairs = insertComments(pairs, k.Comments, (*Comment).String)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
 
 	return out.String()
 }
+
+// IsComment reports whether the object has only comments.
+func (k *KeyValueObject) IsComment() bool {
+	return len(k.KV) == 0 && len(k.Comments) > 0
+}
+
 func (k *KeyValueObject) KVPairs() map[string]Expression {
 	kvPairs := make(map[string]Expression)
 	for _, kv := range k.KV {
@@ -114,3 +147,44 @@ type KeyValuePair struct {
 func (k *KeyValuePair) String() string {
 	return fmt.Sprintf("%s: %s", k.Key.String(), k.Value.String())
 }
+
+// CommentKey is the key of comments, whose pairs are kept apart from the others so that they are never evaluated.
+const CommentKey = "//"
+
+// Comment is the value of the comment key.
+type Comment struct {
+	// Token is the token of the comment key
+	Token token.Token
+	Value Expression
+	// Index is the number of the pairs or the elements before the comment, which keeps its place.
+	Index int
+}
+
+func (c *Comment) String() string {
+	return fmt.Sprintf("\"%s\": %s", CommentKey, c.Value.String())
+}
+
+// insertComments inserts the comments formatted by format into the items at their places.
+func insertComments(items []string, comments []*Comment, format func(*Comment) string) []string {
+	if len(comments) == 0 {
+		return items
+	}
+
+	inserted := make([]string, 0, len(items)+len(comments))
+	next := 0
+	for i := 0; i <= len(items); i++ {
+		for next < len(comments) && comments[next].Index <= i {
+			inserted = append(inserted, format(comments[next]))
+			next++
+		}
+		if i < len(items) {
+			inserted = append(inserted, items[i])
+		}
+	}
+	// the comments whose places are out of the items
+	for ; next < len(comments); next++ {
+		inserted = append(inserted, format(comments[next]))
+	}
+
+	return inserted
+}
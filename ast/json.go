@@ -0,0 +1,113 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToJSON serializes the expression back to JSON source.
+// keys of objects keep their original case, and the output is indented by indent when it is not empty.
+func ToJSON(exp Expression, indent string) (string, error) {
+	var out bytes.Buffer
+	if err := writeJSON(&out, exp); err != nil {
+		return "", err
+	}
+
+	if indent == "" {
+		return out.String(), nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", indent); err != nil {
+		return "", err
+	}
+	return indented.String(), nil
+}
+
+func writeJSON(out *bytes.Buffer, exp Expression) error {
+	switch exp := exp.(type) {
+	case nil:
+		// an empty program has no expression
+		out.WriteString("null")
+	case *IntegerLiteral, *BigIntegerLiteral, *FloatLiteral, *Boolean:
+		out.WriteString(exp.TokenLiteral())
+	case *PrefixAtom:
+		out.WriteString(exp.Operator)
+		return writeJSON(out, exp.Right)
+	case *StringLiteral:
+		out.WriteString(QuoteJSONString(exp.Value))
+	case *Array:
+		elements := make([]string, len(exp.Elements))
+		for i, el := range exp.Elements {
+			elJSON, err := elementJSON(el)
+			if err != nil {
+				return err
+			}
+			elements[i] = elJSON
+		}
+		elements, err := insertCommentsJSON(elements, exp.Comments, "{", "}")
+		if err != nil {
+			return err
+		}
+
+		out.WriteString("[")
+		out.WriteString(strings.Join(elements, ","))
+		out.WriteString("]")
+	case *KeyValueObject:
+		pairs := make([]string, len(exp.KV))
+		for i, kv := range exp.KV {
+			valueJSON, err := elementJSON(kv.Value)
+			if err != nil {
+				return err
+			}
+			pairs[i] = QuoteJSONString(kv.Key.Token.Literal) + ":" + valueJSON
+		}
+		pairs, err := insertCommentsJSON(pairs, exp.Comments, "", "")
+		if err != nil {
+			return err
+		}
+
+		out.WriteString("{")
+		out.WriteString(strings.Join(pairs, ","))
+		out.WriteString("}")
+	default:
+		return fmt.Errorf("cannot serialize %s to JSON", exp)
+	}
+
+	return nil
+}
+
+func elementJSON(exp Expression) (string, error) {
+	var out bytes.Buffer
+	if err := writeJSON(&out, exp); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// insertCommentsJSON inserts the comments as the pairs of the comment key into the items.
+// the pairs are surrounded by prefix and suffix, which make objects of them in arrays.
+func insertCommentsJSON(items []string, comments []*Comment, prefix, suffix string) ([]string, error) {
+	var err error
+	inserted := insertComments(items, comments, func(c *Comment) string {
+		valueJSON, valueErr := elementJSON(c.Value)
+		if valueErr != nil && err == nil {
+			err = valueErr
+		}
+		return prefix + QuoteJSONString(CommentKey) + ":" + valueJSON + suffix
+	})
+	return inserted, err
+}
+
+// QuoteJSONString quotes the string as a JSON string without escaping HTML characters.
+// the object package uses it as well, so that values are quoted in the same way as the source.
+func QuoteJSONString(str string) string {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	// encoding a string never fails
+	_ = encoder.Encode(str)
+	return string(bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+}
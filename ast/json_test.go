@@ -0,0 +1,80 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/JunNishimura/jsop/token"
+)
+
+func TestToJSON(t *testing.T) {
+	withComments := testArray(testInteger(1), testObject("key", testInteger(2)))
+	withComments.Comments = []*Comment{
+		{Value: testString("first"), Index: 0},
+		{Value: testString("last"), Index: 2},
+	}
+
+	objWithComments := testObject("a", testInteger(1), "b", testInteger(2))
+	objWithComments.Comments = []*Comment{{Value: testString("between"), Index: 1}}
+
+	// the parser lowercases the key, and keeps the original in the token
+	upperKey := &KeyValueObject{
+		Token: token.Token{Type: token.LBRACE, Literal: "{"},
+		KV: []*KeyValuePair{
+			{
+				Key:   &StringLiteral{Token: token.Token{Type: token.STRING, Literal: "Command"}, Value: "command"},
+				Value: testString("<&>"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		input    Expression
+		indent   string
+		expected string
+	}{
+		{
+			name:     "empty program",
+			input:    nil,
+			expected: "null",
+		},
+		{
+			name:     "nested expression",
+			input:    testTree(),
+			expected: `[1,{"command":{"symbol":"-","args":[2,-3]}}]`,
+		},
+		{
+			name:     "comments in array",
+			input:    withComments,
+			expected: `[{"//":"first"},1,{"key":2},{"//":"last"}]`,
+		},
+		{
+			name:     "comments in object",
+			input:    objWithComments,
+			expected: `{"a":1,"//":"between","b":2}`,
+		},
+		{
+			name:     "original case of key",
+			input:    upperKey,
+			expected: `{"Command":"<&>"}`,
+		},
+		{
+			name:     "indent",
+			input:    testObject("key", testArray(testInteger(1))),
+			indent:   "  ",
+			expected: "{\n  \"key\": [\n    1\n  ]\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON(tt.input, tt.indent)
+			if err != nil {
+				t.Fatalf("ToJSON() error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong JSON. expected=%q, got=%q", tt.expected, got)
+			}
+		})
+	}
+}
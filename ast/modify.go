@@ -1,28 +1,99 @@
 package ast
 
+import "math/big"
+
 type ModifierFun func(Expression) Expression
 
+// Modify returns the expression whose nodes are replaced by the modifier in post-order.
+// the given expression is never changed. the nodes are copied only when their children are replaced,
+// and the others are shared with the given expression.
 func Modify(exp Expression, modifier ModifierFun) Expression {
 	switch e := exp.(type) {
 	case *PrefixAtom:
-		e.Right = Modify(e.Right, modifier)
+		newRight := Modify(e.Right, modifier)
+		if newRight != e.Right {
+			exp = &PrefixAtom{Token: e.Token, Operator: e.Operator, Right: newRight}
+		}
 	case *Array:
+		var newElements []Expression
 		for i, el := range e.Elements {
-			e.Elements[i] = Modify(el, modifier)
+			newEl := Modify(el, modifier)
+			if newEl != el && newElements == nil {
+				newElements = make([]Expression, len(e.Elements))
+				copy(newElements, e.Elements[:i])
+			}
+			if newElements != nil {
+				newElements[i] = newEl
+			}
+		}
+		if newElements != nil {
+			exp = &Array{Token: e.Token, Elements: newElements, Comments: e.Comments}
 		}
 	case *KeyValueObject:
-		newKV := make([]*KeyValuePair, len(e.KV))
+		var newKV []*KeyValuePair
 		for i, kv := range e.KV {
-			newKeyExp := Modify(kv.Key, modifier)
-			newKey, ok := newKeyExp.(*StringLiteral)
+			// keys must be strings, so the key is kept when the modifier replaces it with another expression
+			newKey, ok := Modify(kv.Key, modifier).(*StringLiteral)
 			if !ok {
-				panic("key must be StringLiteral")
+				newKey = kv.Key
 			}
 			newValue := Modify(kv.Value, modifier)
-			newKV[i] = &KeyValuePair{Key: newKey, Value: newValue}
+			if (newKey != kv.Key || newValue != kv.Value) && newKV == nil {
+				newKV = make([]*KeyValuePair, len(e.KV))
+				copy(newKV, e.KV[:i])
+			}
+			if newKV != nil {
+				newKV[i] = &KeyValuePair{Key: newKey, Value: newValue}
+			}
+		}
+		if newKV != nil {
+			exp = &KeyValueObject{Token: e.Token, KV: newKV, Comments: e.Comments}
 		}
-		e.KV = newKV
 	}
 
 	return modifier(exp)
 }
+
+// Clone returns the deep copy of the expression.
+func Clone(exp Expression) Expression {
+	switch e := exp.(type) {
+	case *IntegerLiteral:
+		return &IntegerLiteral{Token: e.Token, Value: e.Value}
+	case *BigIntegerLiteral:
+		return &BigIntegerLiteral{Token: e.Token, Value: new(big.Int).Set(e.Value)}
+	case *FloatLiteral:
+		return &FloatLiteral{Token: e.Token, Value: e.Value}
+	case *StringLiteral:
+		return &StringLiteral{Token: e.Token, Value: e.Value}
+	case *Boolean:
+		return &Boolean{Token: e.Token, Value: e.Value}
+	case *PrefixAtom:
+		return &PrefixAtom{Token: e.Token, Operator: e.Operator, Right: Clone(e.Right)}
+	case *Array:
+		elements := make([]Expression, len(e.Elements))
+		for i, el := range e.Elements {
+			elements[i] = Clone(el)
+		}
+		return &Array{Token: e.Token, Elements: elements, Comments: cloneComments(e.Comments)}
+	case *KeyValueObject:
+		kvs := make([]*KeyValuePair, len(e.KV))
+		for i, kv := range e.KV {
+			kvs[i] = &KeyValuePair{Key: Clone(kv.Key).(*StringLiteral), Value: Clone(kv.Value)}
+		}
+		return &KeyValueObject{Token: e.Token, KV: kvs, Comments: cloneComments(e.Comments)}
+	default:
+		return exp
+	}
+}
+
+func cloneComments(comments []*Comment) []*Comment {
+	if comments == nil {
+		return nil
+	}
+
+	cloned := make([]*Comment, len(comments))
+	for i, c := range comments {
+		cloned[i] = &Comment{Token: c.Token, Value: Clone(c.Value), Index: c.Index}
+	}
+	return cloned
+}
@@ -0,0 +1,170 @@
+package ast
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/JunNishimura/jsop/token"
+)
+
+// replaceInteger returns the modifier which replaces the integer from with to.
+func replaceInteger(from, to int64) ModifierFun {
+	return func(exp Expression) Expression {
+		integer, ok := exp.(*IntegerLiteral)
+		if !ok || integer.Value != from {
+			return exp
+		}
+		return testInteger(to)
+	}
+}
+
+func TestModify(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    Expression
+		modifier ModifierFun
+		expected string
+	}{
+		{
+			name:     "integer",
+			input:    testInteger(1),
+			modifier: replaceInteger(1, 2),
+			expected: "2",
+		},
+		{
+			name:     "element of array",
+			input:    testArray(testInteger(1), testInteger(2)),
+			modifier: replaceInteger(2, 20),
+			expected: testArray(testInteger(1), testInteger(20)).String(),
+		},
+		{
+			name:     "value of object",
+			input:    testObject("key", testInteger(1)),
+			modifier: replaceInteger(1, 10),
+			expected: testObject("key", testInteger(10)).String(),
+		},
+		{
+			name:     "operand of prefix",
+			input:    &PrefixAtom{Token: token.Token{Type: token.MINUS, Literal: "-"}, Operator: "-", Right: testInteger(1)},
+			modifier: replaceInteger(1, 5),
+			expected: "-5",
+		},
+		{
+			name:     "nested expression",
+			input:    testTree(),
+			modifier: replaceInteger(3, 30),
+			expected: testArray(
+				testInteger(1),
+				testObject("command", testObject(
+					"symbol", testString("-"),
+					"args", testArray(testInteger(2), &PrefixAtom{Token: token.Token{Type: token.MINUS, Literal: "-"}, Operator: "-", Right: testInteger(30)}),
+				)),
+			).String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tt.input.String()
+
+			modified := Modify(tt.input, tt.modifier)
+			if modified.String() != tt.expected {
+				t.Errorf("wrong modified expression. expected=%s, got=%s", tt.expected, modified.String())
+			}
+			if tt.input.String() != before {
+				t.Errorf("input is changed. expected=%s, got=%s", before, tt.input.String())
+			}
+		})
+	}
+}
+
+func TestModifyKeepsKeys(t *testing.T) {
+	input := testObject("key", testInteger(1))
+
+	// the key cannot be replaced with an expression other than a string
+	modified := Modify(input, func(exp Expression) Expression {
+		if str, ok := exp.(*StringLiteral); ok && str.Value == "key" {
+			return testInteger(2)
+		}
+		return exp
+	})
+
+	modifiedObj, ok := modified.(*KeyValueObject)
+	if !ok {
+		t.Fatalf("modified is not KeyValueObject. got=%T", modified)
+	}
+	if modifiedObj.KV[0].Key != input.KV[0].Key {
+		t.Errorf("key must be kept. got=%s", modifiedObj.KV[0].Key)
+	}
+}
+
+func TestModifySharesUntouchedSubtrees(t *testing.T) {
+	untouched := testArray(testInteger(1))
+	touched := testArray(testInteger(2))
+	input := testObject("untouched", untouched, "touched", touched)
+
+	modified, ok := Modify(input, replaceInteger(2, 20)).(*KeyValueObject)
+	if !ok {
+		t.Fatalf("modified is not KeyValueObject. got=%T", modified)
+	}
+	if modified == input {
+		t.Errorf("modified object must be a copy of the input")
+	}
+	if modified.KV[0].Value != untouched {
+		t.Errorf("untouched subtree must be shared with the input")
+	}
+	if modified.KV[1].Value == touched {
+		t.Errorf("touched subtree must be a copy of the input")
+	}
+
+	if unchanged := Modify(input, replaceInteger(3, 30)); unchanged != input {
+		t.Errorf("expression must be returned as it is when nothing is replaced")
+	}
+}
+
+func TestClone(t *testing.T) {
+	input := testArray(
+		testTree(),
+		&BigIntegerLiteral{Token: token.Token{Type: token.INT, Literal: "18446744073709551616"}, Value: new(big.Int).Lsh(big.NewInt(1), 64)},
+		&FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: "1.5"}, Value: 1.5},
+		&Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true},
+	)
+	input.Comments = []*Comment{{Value: testString("comment"), Index: 1}}
+
+	cloned, ok := Clone(input).(*Array)
+	if !ok {
+		t.Fatalf("cloned is not Array. got=%T", cloned)
+	}
+	if cloned.String() != input.String() {
+		t.Fatalf("cloned expression is different. expected=%s, got=%s", input.String(), cloned.String())
+	}
+
+	// every node must be a copy, since both trees are visited in the same order
+	var inputNodes, clonedNodes []Expression
+	Inspect(input, func(exp Expression) bool {
+		inputNodes = append(inputNodes, exp)
+		return true
+	})
+	Inspect(cloned, func(exp Expression) bool {
+		clonedNodes = append(clonedNodes, exp)
+		return true
+	})
+	if len(inputNodes) != len(clonedNodes) {
+		t.Fatalf("wrong number of nodes. expected=%d, got=%d", len(inputNodes), len(clonedNodes))
+	}
+	for i := range inputNodes {
+		if inputNodes[i] == clonedNodes[i] {
+			t.Errorf("node %s is shared with the input", inputNodes[i])
+		}
+	}
+
+	if cloned.Comments[0] == input.Comments[0] || cloned.Comments[0].Value == input.Comments[0].Value {
+		t.Errorf("comment is shared with the input")
+	}
+
+	clonedBigInt := cloned.Elements[1].(*BigIntegerLiteral)
+	clonedBigInt.Value.SetInt64(0)
+	if input.Elements[1].(*BigIntegerLiteral).Value.Sign() == 0 {
+		t.Errorf("value of big integer is shared with the input")
+	}
+}
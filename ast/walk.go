@@ -0,0 +1,109 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Visitor is called by Walk for each node in depth-first order.
+type Visitor interface {
+	// Enter is called before the children of the node are visited.
+	// the children are skipped when it returns false.
+	Enter(node Expression, path Path) bool
+	// Leave is called after the children of the node are visited, or skipped.
+	Leave(node Expression, path Path)
+}
+
+// Step is a step from a parent to its child in the path.
+type Step struct {
+	// Parent is the Array, KeyValueObject or PrefixAtom containing the child.
+	Parent Expression
+	// Index is the index of the element in the array, or of the key/value pair in the object.
+	Index int
+	// Key is the key of the pair when the parent is an object, and nil otherwise.
+	// both the key and the value of a pair have the same step.
+	Key *StringLiteral
+}
+
+// Path is the list of steps from the root to the node. it is empty for the root.
+type Path []Step
+
+// Parent returns the node containing the node at the end of the path, or nil for the root.
+func (p Path) Parent() Expression {
+	if len(p) == 0 {
+		return nil
+	}
+	return p[len(p)-1].Parent
+}
+
+// String returns the path like [1].command.args[0].
+func (p Path) String() string {
+	var out bytes.Buffer
+
+	for _, step := range p {
+		switch {
+		case step.Key != nil:
+			out.WriteString(".")
+			out.WriteString(step.Key.Token.Literal)
+		case isArray(step.Parent):
+			out.WriteString(fmt.Sprintf("[%d]", step.Index))
+		}
+	}
+
+	return out.String()
+}
+
+func isArray(exp Expression) bool {
+	_, ok := exp.(*Array)
+	return ok
+}
+
+// Walk traverses the expression in depth-first order, calling the visitor's Enter and Leave for each node.
+// the keys of an object are visited as well as the values, while comments are not.
+func Walk(exp Expression, visitor Visitor) {
+	walk(exp, visitor, Path{})
+}
+
+func walk(exp Expression, visitor Visitor, path Path) {
+	if exp == nil {
+		return
+	}
+
+	if visitor.Enter(exp, path) {
+		switch e := exp.(type) {
+		case *PrefixAtom:
+			walk(e.Right, visitor, appendStep(path, Step{Parent: e}))
+		case *Array:
+			for i, el := range e.Elements {
+				walk(el, visitor, appendStep(path, Step{Parent: e, Index: i}))
+			}
+		case *KeyValueObject:
+			for i, kv := range e.KV {
+				step := Step{Parent: e, Index: i, Key: kv.Key}
+				walk(kv.Key, visitor, appendStep(path, step))
+				walk(kv.Value, visitor, appendStep(path, step))
+			}
+		}
+	}
+
+	visitor.Leave(exp, path)
+}
+
+// appendStep copies the path so that the visitor can keep it after the walk goes on.
+func appendStep(path Path, step Step) Path {
+	newPath := make(Path, len(path), len(path)+1)
+	copy(newPath, path)
+	return append(newPath, step)
+}
+
+// inspector is the visitor which calls the function only on entering nodes.
+type inspector func(Expression) bool
+
+func (f inspector) Enter(node Expression, _ Path) bool { return f(node) }
+func (f inspector) Leave(Expression, Path)             {}
+
+// Inspect traverses the expression in depth-first order like Walk, calling f for each node.
+// the children of the node are skipped when f returns false.
+func Inspect(exp Expression, f func(Expression) bool) {
+	Walk(exp, inspector(f))
+}
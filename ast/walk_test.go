@@ -0,0 +1,217 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/JunNishimura/jsop/token"
+)
+
+func testInteger(value int64) *IntegerLiteral {
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10)}, Value: value}
+}
+
+func testString(value string) *StringLiteral {
+	return &StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}, Value: value}
+}
+
+func testArray(elements ...Expression) *Array {
+	return &Array{Token: token.Token{Type: token.LBRACKET, Literal: "["}, Elements: elements}
+}
+
+// testObject makes an object from the keys and the values in turn.
+func testObject(keyValues ...any) *KeyValueObject {
+	obj := &KeyValueObject{Token: token.Token{Type: token.LBRACE, Literal: "{"}}
+	for i := 0; i < len(keyValues); i += 2 {
+		obj.KV = append(obj.KV, &KeyValuePair{Key: testString(keyValues[i].(string)), Value: keyValues[i+1].(Expression)})
+	}
+	return obj
+}
+
+// testTree makes the expression of [1, {"command": {"symbol": "-", "args": [2, -3]}}].
+func testTree() *Array {
+	return testArray(
+		testInteger(1),
+		testObject("command", testObject(
+			"symbol", testString("-"),
+			"args", testArray(testInteger(2), &PrefixAtom{Token: token.Token{Type: token.MINUS, Literal: "-"}, Operator: "-", Right: testInteger(3)}),
+		)),
+	)
+}
+
+type visit struct {
+	event string
+	node  Expression
+	path  Path
+}
+
+// recorder records the visits, and skips the children of the nodes for which skip returns true.
+type recorder struct {
+	visits []visit
+	skip   func(Expression) bool
+}
+
+func (r *recorder) Enter(node Expression, path Path) bool {
+	r.visits = append(r.visits, visit{event: "enter", node: node, path: path})
+	return r.skip == nil || !r.skip(node)
+}
+
+func (r *recorder) Leave(node Expression, path Path) {
+	r.visits = append(r.visits, visit{event: "leave", node: node, path: path})
+}
+
+// strings formats the visits after the walk, so that the paths kept by the visitor are checked as well.
+func (r *recorder) strings() []string {
+	result := make([]string, len(r.visits))
+	for i, v := range r.visits {
+		result[i] = fmt.Sprintf("%s %s %q", v.event, nodeLabel(v.node), v.path.String())
+	}
+	return result
+}
+
+func nodeLabel(node Expression) string {
+	switch node.(type) {
+	case *Array:
+		return "array"
+	case *KeyValueObject:
+		return "object"
+	case *PrefixAtom:
+		return "prefix"
+	default:
+		return node.String()
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name     string
+		skip     func(Expression) bool
+		expected []string
+	}{
+		{
+			name: "enter and leave every node in depth-first order",
+			expected: []string{
+				`enter array ""`,
+				`enter 1 "[0]"`,
+				`leave 1 "[0]"`,
+				`enter object "[1]"`,
+				`enter "command" "[1].command"`,
+				`leave "command" "[1].command"`,
+				`enter object "[1].command"`,
+				`enter "symbol" "[1].command.symbol"`,
+				`leave "symbol" "[1].command.symbol"`,
+				`enter "-" "[1].command.symbol"`,
+				`leave "-" "[1].command.symbol"`,
+				`enter "args" "[1].command.args"`,
+				`leave "args" "[1].command.args"`,
+				`enter array "[1].command.args"`,
+				`enter 2 "[1].command.args[0]"`,
+				`leave 2 "[1].command.args[0]"`,
+				`enter prefix "[1].command.args[1]"`,
+				`enter 3 "[1].command.args[1]"`,
+				`leave 3 "[1].command.args[1]"`,
+				`leave prefix "[1].command.args[1]"`,
+				`leave array "[1].command.args"`,
+				`leave object "[1].command"`,
+				`leave object "[1]"`,
+				`leave array ""`,
+			},
+		},
+		{
+			name: "skip the children of the node",
+			skip: func(node Expression) bool {
+				obj, ok := node.(*KeyValueObject)
+				return ok && obj.KVPairs()["symbol"] != nil
+			},
+			expected: []string{
+				`enter array ""`,
+				`enter 1 "[0]"`,
+				`leave 1 "[0]"`,
+				`enter object "[1]"`,
+				`enter "command" "[1].command"`,
+				`leave "command" "[1].command"`,
+				`enter object "[1].command"`,
+				`leave object "[1].command"`,
+				`leave object "[1]"`,
+				`leave array ""`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{skip: tt.skip}
+			Walk(testTree(), r)
+
+			got := r.strings()
+			if len(got) != len(tt.expected) {
+				t.Fatalf("wrong number of visits. expected=%d, got=%d\n%v", len(tt.expected), len(got), got)
+			}
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Errorf("visits[%d] is wrong. expected=%s, got=%s", i, tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPathParent(t *testing.T) {
+	tree := testTree()
+	command := tree.Elements[1].(*KeyValueObject).KV[0].Value.(*KeyValueObject)
+	args := command.KV[1].Value.(*Array)
+	minus := args.Elements[1].(*PrefixAtom)
+
+	tests := []struct {
+		name     string
+		node     Expression
+		expected Expression
+	}{
+		{name: "root has no parent", node: tree, expected: nil},
+		{name: "parent of element", node: args.Elements[0], expected: args},
+		{name: "parent of value", node: args, expected: command},
+		{name: "parent of key", node: command.KV[1].Key, expected: command},
+		{name: "parent of prefix operand", node: minus.Right, expected: minus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			Walk(tree, r)
+
+			found := false
+			for _, v := range r.visits {
+				if v.node != tt.node {
+					continue
+				}
+				found = true
+				if parent := v.path.Parent(); parent != tt.expected {
+					t.Errorf("wrong parent of %s. expected=%v, got=%v", tt.node, tt.expected, parent)
+				}
+			}
+			if !found {
+				t.Fatalf("node %s is not visited", tt.node)
+			}
+		})
+	}
+}
+
+func TestWalkSkipsComments(t *testing.T) {
+	tree := testArray(testInteger(1))
+	tree.Comments = []*Comment{{Value: testString("comment"), Index: 0}}
+
+	r := &recorder{}
+	Walk(tree, r)
+
+	expected := []string{
+		`enter array ""`,
+		`enter 1 "[0]"`,
+		`leave 1 "[0]"`,
+		`leave array ""`,
+	}
+	got := r.strings()
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("wrong visits. expected=%v, got=%v", expected, got)
+	}
+}
@@ -1,104 +1,440 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/JunNishimura/jsop/ast"
 	"github.com/JunNishimura/jsop/evaluator"
 	"github.com/JunNishimura/jsop/lexer"
 	"github.com/JunNishimura/jsop/object"
 	"github.com/JunNishimura/jsop/parser"
+	"github.com/JunNishimura/jsop/token"
 )
 
+const usage = "Usage: ./jsop [expand] [--overflow=promote|error] [--output=inspect|json|none] [--json] [--print=last|all|none] [--allow-env] [--allow-any-ext] [--dialect=json|jsonc|json5] <filename | - | -e program> [args...]"
+
+// expandCommand is the subcommand which prints the program whose macros are expanded, instead of running it.
+const expandCommand = "expand"
+
+// stdinPath is the file path which means reading the program from standard input.
+const stdinPath = "-"
+
+// output formats of the result
+const (
+	outputInspect = "inspect"
+	outputJSON    = "json"
+	outputNone    = "none"
+)
+
+// modes to choose which top-level results to print
+const (
+	printLast = "last"
+	printAll  = "all"
+	printNone = "none"
+)
+
+type options struct {
+	filePath string
+	// inlineProgram is the program given by -e, which is used instead of the file when isInline is true
+	inlineProgram string
+	isInline      bool
+	// args are the rest of command line arguments, which are bound to $ARGS
+	args     []string
+	overflow evaluator.OverflowMode
+	output   string
+	print    string
+	allowEnv bool
+	dialect  lexer.Dialect
+}
+
+// jsopExtensions are the file extensions accepted as jsop programs without --allow-any-ext.
+var jsopExtensions = []string{".jsop", ".jsop.json"}
+
 func Run() error {
-	filePath, err := parseCmdArgs()
+	if len(os.Args) > 1 && os.Args[1] == expandCommand {
+		return runExpand(os.Args[2:])
+	}
+
+	opts, err := parseCmdArgs(os.Args[1:])
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Open(filePath)
+	results, err := execute(opts)
 	if err != nil {
-		return fmt.Errorf("fail to open file: %s", err)
+		if opts.output == outputJSON {
+			return printJSONError(jsonErrorOf(err))
+		}
+		return err
 	}
-	defer file.Close()
 
-	// read file at once
-	bytes, err := io.ReadAll(file)
+	for _, result := range selectResults(results, opts.print) {
+		if err := printResult(result, opts.output); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runExpand prints the program whose macros are expanded as formatted JSON.
+func runExpand(args []string) error {
+	opts, err := parseCmdArgs(args)
 	if err != nil {
-		return fmt.Errorf("fail to read file: %s", err)
+		return err
+	}
+
+	expanded, err := expandProgram(opts, newEnvironment(opts))
+	if err != nil {
+		if opts.output == outputJSON {
+			return printJSONError(jsonErrorOf(err))
+		}
+		return err
+	}
+
+	out, err := ast.ToJSON(expanded, "  ")
+	if err != nil {
+		return fmt.Errorf("fail to serialize expanded program: %s", err)
+	}
+	fmt.Println(out)
+
+	return nil
+}
+
+func execute(opts *options) ([]object.Object, error) {
+	env := newEnvironment(opts)
+
+	expanded, err := expandProgram(opts, env)
+	if err != nil {
+		return nil, err
+	}
+	// checked after expansion since macro calls have their own keys
+	if err := checkDataFile(expanded); err != nil {
+		return nil, err
+	}
+
+	// evaluate expanded program
+	return evaluator.EvalProgram(expanded, env), nil
+}
+
+// newEnvironment applies the options to the evaluator, and makes the environment where $ARGS is bound.
+// it is shared by running and expanding, since macros are evaluated in the same way as the program.
+func newEnvironment(opts *options) *object.Environment {
+	evaluator.SetOverflowMode(opts.overflow)
+	evaluator.SetAllowEnv(opts.allowEnv)
+
+	env := object.NewEnvironment()
+	env.Set("$ARGS", argsArray(opts.args))
+	return env
+}
+
+// expandProgram reads and parses the program, and expands the macros defined in it.
+func expandProgram(opts *options, env *object.Environment) (ast.Expression, error) {
+	input, err := readProgram(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	// parse program
-	l := lexer.New(string(bytes))
+	l := lexer.NewWithDialect(input, opts.dialect)
 	p := parser.New(l)
 	program, err := p.ParseProgram()
 	if err != nil {
-		return fmt.Errorf("fail to parse program: %s", err)
+		return nil, fmt.Errorf("fail to parse program: %w", err)
 	}
 
-	// evaluate program
-	env := object.NewEnvironment()
-
 	// define macros
 	if err := evaluator.DefineMacros(program, env); err != nil {
-		return fmt.Errorf("fail to define macros: %s", err)
+		return nil, fmt.Errorf("fail to define macros: %s", err)
 	}
 
 	// expand macros
-	expanded := evaluator.ExpandMacros(program, env)
+	expanded, err := evaluator.ExpandMacros(program, env)
+	if err != nil {
+		return nil, fmt.Errorf("fail to expand macros: %w", err)
+	}
 
-	// evaluate expanded program
-	evaluated := evaluator.Eval(expanded, env)
-	finalResult := extractFinalEvaluation(evaluated)
+	return expanded, nil
+}
+
+// selectResults chooses the results to print by the print mode.
+// an error is always printed even if the mode is none.
+func selectResults(results []object.Object, mode string) []object.Object {
+	if len(results) == 0 {
+		if mode == printLast {
+			// an empty program evaluates to null
+			return []object.Object{evaluator.Null}
+		}
+		return results
+	}
+
+	last := results[len(results)-1]
+	switch mode {
+	case printAll:
+		return results
+	case printNone:
+		if last.Type() == object.ERROR_OBJ {
+			return []object.Object{last}
+		}
+		return nil
+	default:
+		return []object.Object{last}
+	}
+}
+
+func printResult(result object.Object, output string) error {
+	switch output {
+	case outputJSON:
+		if errObj, ok := result.(*object.Error); ok {
+			return printJSONError(errObj.Message, jsonPositionOf(errObj.Pos))
+		}
+		jsonResult, err := object.ToJSON(result, "", false)
+		if err != nil {
+			return printJSONError(fmt.Sprintf("fail to serialize result: %s", err), nil)
+		}
+		fmt.Println(jsonResult)
+	case outputInspect:
+		fmt.Println(result.Inspect())
+	}
+
+	return nil
+}
+
+func argsArray(args []string) *object.Array {
+	elements := make([]object.Object, len(args))
+	for i, arg := range args {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// readProgram reads the program from -e, standard input or the file.
+func readProgram(opts *options) (string, error) {
+	if opts.isInline {
+		return opts.inlineProgram, nil
+	}
+
+	if opts.filePath == stdinPath {
+		bytes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("fail to read standard input: %s", err)
+		}
+		return string(bytes), nil
+	}
 
-	fmt.Println(finalResult.Inspect())
+	file, err := os.Open(opts.filePath)
+	if err != nil {
+		return "", fmt.Errorf("fail to open file: %s", err)
+	}
+	defer file.Close()
+
+	// read file at once
+	bytes, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("fail to read file: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+type jsonError struct {
+	Error jsonErrorDetail `json:"error"`
+}
+
+type jsonErrorDetail struct {
+	Message  string        `json:"message"`
+	Position *jsonPosition `json:"position"`
+}
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// printJSONError prints the error as a JSON object. position is null when it is unknown.
+func printJSONError(message string, position *jsonPosition) error {
+	out, err := json.Marshal(jsonError{
+		Error: jsonErrorDetail{Message: message, Position: position},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
 
 	return nil
 }
 
-func parseCmdArgs() (string, error) {
+// jsonErrorOf returns the message and the position of the error to print as JSON.
+// the position is removed from the message since it is printed separately.
+func jsonErrorOf(err error) (string, *jsonPosition) {
+	var parseErr *parser.ParseError
+	if errors.As(err, &parseErr) {
+		message := strings.Replace(err.Error(), parseErr.Error(), parseErr.Message, 1)
+		return message, jsonPositionOf(parseErr.Pos)
+	}
+	var expansionErr *evaluator.ExpansionError
+	if errors.As(err, &expansionErr) {
+		withoutPos := *expansionErr
+		withoutPos.Pos = token.Position{}
+		message := strings.Replace(err.Error(), expansionErr.Error(), withoutPos.Error(), 1)
+		return message, jsonPositionOf(expansionErr.Pos)
+	}
+	var dataFileErr *dataFileError
+	if errors.As(err, &dataFileErr) {
+		return "the object " + dataFileMessage, jsonPositionOf(dataFileErr.pos)
+	}
+	return err.Error(), nil
+}
+
+func jsonPositionOf(pos token.Position) *jsonPosition {
+	if pos.Line == 0 {
+		return nil
+	}
+	return &jsonPosition{Line: pos.Line, Column: pos.Column}
+}
+
+func parseCmdArgs(args []string) (*options, error) {
+	flags := flag.NewFlagSet("jsop", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	overflow := flags.String("overflow", "promote", "how to handle integer overflow: promote or error")
+	output := flags.String("output", outputInspect, "format of the result: inspect, json or none")
+	jsonOutput := flags.Bool("json", false, "shorthand for --output=json")
+	printMode := flags.String("print", printLast, "results to print: last, all or none")
+	inlineProgram := flags.String("e", "", "program to evaluate instead of a file")
+	allowEnv := flags.Bool("allow-env", false, "allow programs to read environment variables")
+	allowAnyExt := flags.Bool("allow-any-ext", false, "run files with any extension")
+	dialect := flags.String("dialect", string(lexer.JSON), "flavor of JSON to accept: json, jsonc or json5")
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%s. %s", err, usage)
+	}
+
+	opts := &options{allowEnv: *allowEnv}
+	switch *overflow {
+	case "promote":
+		opts.overflow = evaluator.OverflowPromote
+	case "error":
+		opts.overflow = evaluator.OverflowError
+	default:
+		return nil, fmt.Errorf("invalid value for --overflow: %s. Please use promote or error", *overflow)
+	}
+
+	switch *output {
+	case outputInspect, outputJSON, outputNone:
+		opts.output = *output
+	default:
+		return nil, fmt.Errorf("invalid value for --output: %s. Please use inspect, json or none", *output)
+	}
+	if *jsonOutput {
+		opts.output = outputJSON
+	}
+
+	switch *printMode {
+	case printLast, printAll, printNone:
+		opts.print = *printMode
+	default:
+		return nil, fmt.Errorf("invalid value for --print: %s. Please use last, all or none", *printMode)
+	}
+
+	switch lexer.Dialect(*dialect) {
+	case lexer.JSON, lexer.JSONC, lexer.JSON5:
+		opts.dialect = lexer.Dialect(*dialect)
+	default:
+		return nil, fmt.Errorf("invalid value for --dialect: %s. Please use json, jsonc or json5", *dialect)
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "e" {
+			opts.isInline = true
+		}
+	})
+	if opts.isInline {
+		opts.inlineProgram = *inlineProgram
+		opts.args = flags.Args()
+		return opts, nil
+	}
+
 	// check if the user has provided a file to run
-	cmdArgs := os.Args
-	if len(cmdArgs) != 2 {
-		return "", errors.New("please specify a file to run. Usage: ./jsop <filename>")
+	if flags.NArg() == 0 {
+		return nil, fmt.Errorf("please specify a file to run. %s", usage)
+	}
+	opts.args = flags.Args()[1:]
+
+	// standard input has no file extension to check
+	filePath := flags.Arg(0)
+	if filePath == stdinPath {
+		opts.filePath = filePath
+		return opts, nil
 	}
 
 	// check if the file extension is valid
-	filePath := cmdArgs[1]
 	fileName, err := filepath.Abs(filePath)
 	if err != nil {
-		return "", fmt.Errorf("fail to get absolute path of file: %s", err)
+		return nil, fmt.Errorf("fail to get absolute path of file: %s", err)
 	}
-	if !isValidFileExtension(fileName) {
-		return "", errors.New("invalid file extension. Please use .jsop or .jsop.json files")
+	if !*allowAnyExt && !isValidFileExtension(fileName) {
+		if strings.EqualFold(filepath.Ext(fileName), ".json") {
+			return nil, fmt.Errorf("invalid file extension: %s. Please rename jsop programs to .jsop.json, or use --allow-any-ext to run it anyway", filePath)
+		}
+		return nil, fmt.Errorf("invalid file extension: %s. Please use .jsop or .jsop.json files, or use --allow-any-ext to run it anyway", filePath)
 	}
+	opts.filePath = filePath
 
-	return filePath, nil
+	return opts, nil
 }
 
 func isValidFileExtension(fileName string) bool {
 	lowerCaseFileName := strings.ToLower(fileName)
 
-	if filepath.Ext(lowerCaseFileName) == ".jsop" {
-		return true
+	for _, ext := range jsopExtensions {
+		if len(lowerCaseFileName) > len(ext) && strings.HasSuffix(lowerCaseFileName, ext) {
+			return true
+		}
 	}
 
-	fileLen := len(lowerCaseFileName)
-	if fileLen > 10 && lowerCaseFileName[fileLen-10:] == ".jsop.json" {
-		return true
+	return false
+}
+
+// checkDataFile reports an error if the program seems to be JSON data rather than a jsop program,
+// that is, an object at the top level has none of the jsop keywords.
+func checkDataFile(program ast.Expression) error {
+	topLevels := []ast.Expression{program}
+	if array, ok := program.(*ast.Array); ok {
+		topLevels = array.Elements
 	}
 
-	return true
-}
+	for _, exp := range topLevels {
+		kvObj, ok := exp.(*ast.KeyValueObject)
+		if !ok || kvObj.IsComment() {
+			continue
+		}
+		if slices.ContainsFunc(kvObj.KV, func(kv *ast.KeyValuePair) bool {
+			return evaluator.IsSpecialForm(kv.Key.Value)
+		}) {
+			continue
+		}
 
-func extractFinalEvaluation(obj object.Object) object.Object {
-	if array, ok := obj.(*object.Array); ok {
-		return array.Elements[len(array.Elements)-1]
+		return &dataFileError{pos: kvObj.Token.Pos}
 	}
 
-	return obj
+	return nil
+}
+
+// dataFileMessage follows the object in the message of dataFileError.
+const dataFileMessage = "has no jsop keyword such as command or set. The input seems to be JSON data, not a jsop program"
+
+// dataFileError is the error of the input which seems to be JSON data, with the position of the object without jsop keywords.
+type dataFileError struct {
+	pos token.Position
+}
+
+func (e *dataFileError) Error() string {
+	return fmt.Sprintf("the object at line %d, column %d %s", e.pos.Line, e.pos.Column, dataFileMessage)
 }
@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JunNishimura/jsop/evaluator"
+	"github.com/JunNishimura/jsop/lexer"
+	"github.com/JunNishimura/jsop/object"
+	"github.com/JunNishimura/jsop/parser"
+	"github.com/JunNishimura/jsop/token"
+)
+
+func TestIsValidFileExtension(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		expected bool
+	}{
+		{name: "jsop", fileName: "/tmp/program.jsop", expected: true},
+		{name: "jsop.json", fileName: "/tmp/program.jsop.json", expected: true},
+		{name: "upper case", fileName: "/tmp/PROGRAM.JSOP.JSON", expected: true},
+		{name: "plain json", fileName: "/tmp/program.json", expected: false},
+		{name: "other extension", fileName: "/tmp/program.txt", expected: false},
+		{name: "extension only", fileName: ".jsop", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidFileExtension(tt.fileName); got != tt.expected {
+				t.Errorf("isValidFileExtension(%q) is wrong. expected=%t, got=%t", tt.fileName, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseCmdArgsFileExtension(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		expectedFilePath string
+		expectedError    string
+	}{
+		{
+			name:             "jsop file",
+			args:             []string{"program.jsop.json"},
+			expectedFilePath: "program.jsop.json",
+		},
+		{
+			name:             "standard input",
+			args:             []string{"-"},
+			expectedFilePath: "-",
+		},
+		{
+			name:          "plain json file",
+			args:          []string{"program.json"},
+			expectedError: "invalid file extension: program.json. Please rename jsop programs to .jsop.json, or use --allow-any-ext to run it anyway",
+		},
+		{
+			name:          "other file",
+			args:          []string{"program.txt"},
+			expectedError: "invalid file extension: program.txt. Please use .jsop or .jsop.json files, or use --allow-any-ext to run it anyway",
+		},
+		{
+			name:             "plain json file with --allow-any-ext",
+			args:             []string{"--allow-any-ext", "program.json"},
+			expectedFilePath: "program.json",
+		},
+		{
+			name:             "other file with --allow-any-ext",
+			args:             []string{"--allow-any-ext", "program.txt", "arg"},
+			expectedFilePath: "program.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseCmdArgs(tt.args)
+			if tt.expectedError != "" {
+				if err == nil {
+					t.Fatalf("expected error %q, got nil", tt.expectedError)
+				}
+				if err.Error() != tt.expectedError {
+					t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedError, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseCmdArgs() error: %v", err)
+			}
+			if opts.filePath != tt.expectedFilePath {
+				t.Errorf("wrong file path. expected=%q, got=%q", tt.expectedFilePath, opts.filePath)
+			}
+		})
+	}
+}
+
+func TestParseCmdArgsInlineProgram(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		expectedProgram string
+		expectedArgs    []string
+	}{
+		{
+			name:            "inline program",
+			args:            []string{"-e", `{"command": {"symbol": "+", "args": [1, 2]}}`},
+			expectedProgram: `{"command": {"symbol": "+", "args": [1, 2]}}`,
+			expectedArgs:    []string{},
+		},
+		{
+			name:            "empty inline program",
+			args:            []string{"-e", ""},
+			expectedProgram: "",
+			expectedArgs:    []string{},
+		},
+		{
+			name:            "args after inline program",
+			args:            []string{"--print=all", "-e", `"$ARGS"`, "a", "program.txt"},
+			expectedProgram: `"$ARGS"`,
+			expectedArgs:    []string{"a", "program.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseCmdArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseCmdArgs() error: %v", err)
+			}
+			if !opts.isInline {
+				t.Fatalf("program must be inline")
+			}
+			if !reflect.DeepEqual(opts.args, tt.expectedArgs) {
+				t.Errorf("wrong args. expected=%q, got=%q", tt.expectedArgs, opts.args)
+			}
+
+			program, err := readProgram(opts)
+			if err != nil {
+				t.Fatalf("readProgram() error: %v", err)
+			}
+			if program != tt.expectedProgram {
+				t.Errorf("wrong program. expected=%q, got=%q", tt.expectedProgram, program)
+			}
+		})
+	}
+}
+
+func TestReadProgramFromStdin(t *testing.T) {
+	input := `{"command": {"symbol": "+", "args": [1, 2]}}`
+
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatalf("CreateTemp() error: %v", err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.WriteString(input); err != nil {
+		t.Fatalf("WriteString() error: %v", err)
+	}
+	if _, err := stdin.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error: %v", err)
+	}
+
+	original := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = original }()
+
+	opts, err := parseCmdArgs([]string{"-", "a"})
+	if err != nil {
+		t.Fatalf("parseCmdArgs() error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.args, []string{"a"}) {
+		t.Errorf("wrong args. expected=%q, got=%q", []string{"a"}, opts.args)
+	}
+
+	program, err := readProgram(opts)
+	if err != nil {
+		t.Fatalf("readProgram() error: %v", err)
+	}
+	if program != input {
+		t.Errorf("wrong program. expected=%q, got=%q", input, program)
+	}
+}
+
+func TestExecuteBindsArgs(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "args.jsop.json")
+	if err := os.WriteFile(filePath, []byte(`"$ARGS"`), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "args after file", args: []string{filePath, "a", "b"}, expected: `["a", "b"]`},
+		{name: "args after inline program", args: []string{"-e", `"$ARGS"`, "a", "b"}, expected: `["a", "b"]`},
+		{name: "no args", args: []string{"-e", `"$ARGS"`}, expected: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseCmdArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseCmdArgs() error: %v", err)
+			}
+
+			results, err := execute(opts)
+			if err != nil {
+				t.Fatalf("execute() error: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("wrong number of results. expected=1, got=%d", len(results))
+			}
+			if results[0].Inspect() != tt.expected {
+				t.Errorf("wrong $ARGS. expected=%s, got=%s", tt.expected, results[0].Inspect())
+			}
+		})
+	}
+}
+
+func TestCheckDataFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "program",
+			input: `[{"set": {"var": "$x", "val": 1}}, {"command": {"symbol": "print", "args": "$x"}}]`,
+		},
+		{
+			name:  "keyword in upper case",
+			input: `{"SET": {"var": "$x", "val": 1}}`,
+		},
+		{
+			name:  "object of only comments",
+			input: `[{"//": "comment"}, 1]`,
+		},
+		{
+			name:  "values other than objects",
+			input: `[1, "two", [3]]`,
+		},
+		{
+			name:     "data object",
+			input:    `{"name": "jsop", "version": 1}`,
+			expected: "the object at line 1, column 1 has no jsop keyword such as command or set. The input seems to be JSON data, not a jsop program",
+		},
+		{
+			name: "data object in array",
+			input: `[
+				{"command": {"symbol": "print", "args": 1}},
+				{"name": "jsop"}
+			]`,
+			expected: "the object at line 3, column 5 has no jsop keyword such as command or set. The input seems to be JSON data, not a jsop program",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			if err != nil {
+				t.Fatalf("ParseProgram() error: %v", err)
+			}
+
+			err = checkDataFile(program)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("checkDataFile() error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tt.expected)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestSelectResults(t *testing.T) {
+	one := &object.Integer{Value: 1}
+	two := &object.Integer{Value: 2}
+	errObj := &object.Error{Message: "error"}
+
+	tests := []struct {
+		name     string
+		results  []object.Object
+		mode     string
+		expected []object.Object
+	}{
+		{name: "last", results: []object.Object{one, two}, mode: printLast, expected: []object.Object{two}},
+		{name: "all", results: []object.Object{one, two}, mode: printAll, expected: []object.Object{one, two}},
+		{name: "none", results: []object.Object{one, two}, mode: printNone, expected: nil},
+		{name: "error with last", results: []object.Object{one, errObj}, mode: printLast, expected: []object.Object{errObj}},
+		{name: "error with all", results: []object.Object{one, errObj}, mode: printAll, expected: []object.Object{one, errObj}},
+		{name: "error with none", results: []object.Object{one, errObj}, mode: printNone, expected: []object.Object{errObj}},
+		{name: "empty program with last", results: []object.Object{}, mode: printLast, expected: []object.Object{evaluator.Null}},
+		{name: "empty program with all", results: []object.Object{}, mode: printAll, expected: nil},
+		{name: "empty program with none", results: []object.Object{}, mode: printNone, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectResults(tt.results, tt.mode)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("wrong number of results. expected=%d, got=%d (%v)", len(tt.expected), len(got), got)
+			}
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Errorf("results[%d] is wrong. expected=%s, got=%s", i, tt.expected[i].Inspect(), got[i].Inspect())
+				}
+			}
+		})
+	}
+}
+
+func TestJSONErrorOf(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		expectedMessage  string
+		expectedPosition *jsonPosition
+	}{
+		{
+			name:             "parse error",
+			err:              fmt.Errorf("fail to parse program: %w", &parser.ParseError{Message: "unexpected token type ]", Pos: token.Position{Line: 4, Column: 19}}),
+			expectedMessage:  "fail to parse program: unexpected token type ]",
+			expectedPosition: &jsonPosition{Line: 4, Column: 19},
+		},
+		{
+			name:             "expansion error",
+			err:              fmt.Errorf("fail to expand macros: %w", &evaluator.ExpansionError{Macro: "m", Message: "must result in QUOTE, got INTEGER", Pos: token.Position{Line: 2, Column: 3}}),
+			expectedMessage:  "fail to expand macros: macro m: must result in QUOTE, got INTEGER",
+			expectedPosition: &jsonPosition{Line: 2, Column: 3},
+		},
+		{
+			name:            "expansion error without position",
+			err:             fmt.Errorf("fail to expand macros: %w", &evaluator.ExpansionError{Macro: "m", Message: "must result in QUOTE, got INTEGER"}),
+			expectedMessage: "fail to expand macros: macro m: must result in QUOTE, got INTEGER",
+		},
+		{
+			name:             "data file error",
+			err:              &dataFileError{pos: token.Position{Line: 1, Column: 1}},
+			expectedMessage:  "the object has no jsop keyword such as command or set. The input seems to be JSON data, not a jsop program",
+			expectedPosition: &jsonPosition{Line: 1, Column: 1},
+		},
+		{
+			name:            "error without position",
+			err:             errors.New("fail to open file: no such file"),
+			expectedMessage: "fail to open file: no such file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, position := jsonErrorOf(tt.err)
+			if message != tt.expectedMessage {
+				t.Errorf("wrong message. expected=%q, got=%q", tt.expectedMessage, message)
+			}
+			if !reflect.DeepEqual(position, tt.expectedPosition) {
+				t.Errorf("wrong position. expected=%+v, got=%+v", tt.expectedPosition, position)
+			}
+		})
+	}
+}
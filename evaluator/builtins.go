@@ -17,14 +17,17 @@ var builtins = map[string]*object.Builtin{
 				return newError("number of arguments to '+' must be more than 0, got %d", len(arrayArg.Elements))
 			}
 
-			var result int64
+			var result object.Object = &object.Integer{Value: 0}
 			for _, arg := range arrayArg.Elements {
-				if arg.Type() != object.INTEGER_OBJ {
-					return newError("argument to '+' must be INTEGER, got %s", arg.Type())
+				if !isNumber(arg) {
+					return newError("argument to '+' must be NUMBER, got %s", arg.Type())
+				}
+				result = evalNumberArithmetic("+", result, arg)
+				if isError(result) {
+					return result
 				}
-				result += arg.(*object.Integer).Value
 			}
-			return &object.Integer{Value: result}
+			return result
 		},
 	},
 	"-": {
@@ -37,19 +40,22 @@ var builtins = map[string]*object.Builtin{
 				return newError("number of arguments to '-' must be more than 1, got %d", len(arrayArg.Elements))
 			}
 
-			var result int64
+			var result object.Object
 			for i, arg := range arrayArg.Elements {
-				if arg.Type() != object.INTEGER_OBJ {
-					return newError("argument to '-' must be INTEGER, got %s", arg.Type())
+				if !isNumber(arg) {
+					return newError("argument to '-' must be NUMBER, got %s", arg.Type())
 				}
 				if i == 0 {
-					result = arg.(*object.Integer).Value
+					result = arg
 				} else {
-					result -= arg.(*object.Integer).Value
+					result = evalNumberArithmetic("-", result, arg)
+					if isError(result) {
+						return result
+					}
 				}
 			}
 
-			return &object.Integer{Value: result}
+			return result
 		},
 	},
 	"*": {
@@ -62,15 +68,18 @@ var builtins = map[string]*object.Builtin{
 				return newError("number of arguments to '*' must be more than 0, got %d", len(arrayArg.Elements))
 			}
 
-			var result int64 = 1
+			var result object.Object = &object.Integer{Value: 1}
 			for _, arg := range arrayArg.Elements {
-				if arg.Type() != object.INTEGER_OBJ {
-					return newError("argument to '*' must be INTEGER, got %s", arg.Type())
+				if !isNumber(arg) {
+					return newError("argument to '*' must be NUMBER, got %s", arg.Type())
+				}
+				result = evalNumberArithmetic("*", result, arg)
+				if isError(result) {
+					return result
 				}
-				result *= arg.(*object.Integer).Value
 			}
 
-			return &object.Integer{Value: result}
+			return result
 		},
 	},
 	"/": {
@@ -83,22 +92,25 @@ var builtins = map[string]*object.Builtin{
 				return newError("number of arguments to '/' must be more than 1, got %d", len(arrayArg.Elements))
 			}
 
-			var result int64
+			var result object.Object
 			for i, arg := range arrayArg.Elements {
-				if arg.Type() != object.INTEGER_OBJ {
-					return newError("argument to '/' must be INTEGER, got %s", arg.Type())
+				if !isNumber(arg) {
+					return newError("argument to '/' must be NUMBER, got %s", arg.Type())
 				}
 				if i == 0 {
-					result = arg.(*object.Integer).Value
+					result = arg
 				} else {
-					if arg.(*object.Integer).Value == 0 {
+					if isZeroNumber(arg) {
 						return newError("division by zero")
 					}
-					result /= arg.(*object.Integer).Value
+					result = evalNumberArithmetic("/", result, arg)
+					if isError(result) {
+						return result
+					}
 				}
 			}
 
-			return &object.Integer{Value: result}
+			return result
 		},
 	},
 	"%": {
@@ -111,21 +123,22 @@ var builtins = map[string]*object.Builtin{
 				return newError("number of arguments to '%%' must be 2, got %d", len(arrayArg.Elements))
 			}
 
-			first, ok := arrayArg.Elements[0].(*object.Integer)
-			if !ok {
-				return newError("first argument to '%%' must be INTEGER, got %s", arrayArg.Elements[0].Type())
+			first := arrayArg.Elements[0]
+			if !isNumber(first) {
+				return newError("first argument to '%%' must be NUMBER, got %s", first.Type())
 			}
-			second, ok := arrayArg.Elements[1].(*object.Integer)
-			if !ok {
-				return newError("second argument to '%%' must be INTEGER, got %s", arrayArg.Elements[1].Type())
+			second := arrayArg.Elements[1]
+			if !isNumber(second) {
+				return newError("second argument to '%%' must be NUMBER, got %s", second.Type())
 			}
 
-			if second.Value == 0 {
+			if isZeroNumber(second) {
 				return newError("division by zero")
 			}
 
-			return &object.Integer{Value: first.Value % second.Value}
+			return evalNumberArithmetic("%", first, second)
 		},
+		Params: []string{"$dividend", "$divisor"},
 	},
 	"==": {
 		Fn: func(args object.Object) object.Object {
@@ -138,7 +151,7 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for i := 0; i < len(arrayArg.Elements)-1; i++ {
-				if arrayArg.Elements[i].Inspect() != arrayArg.Elements[i+1].Inspect() {
+				if !object.Equal(arrayArg.Elements[i], arrayArg.Elements[i+1]) {
 					return False
 				}
 			}
@@ -157,7 +170,7 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for i := 0; i < len(arrayArg.Elements)-1; i++ {
-				if arrayArg.Elements[i].Inspect() != arrayArg.Elements[i+1].Inspect() {
+				if !object.Equal(arrayArg.Elements[i], arrayArg.Elements[i+1]) {
 					return True
 				}
 			}
@@ -176,15 +189,15 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for i := 0; i < len(arrayArg.Elements)-1; i++ {
-				first, ok := arrayArg.Elements[i].(*object.Integer)
-				if !ok {
-					return newError("argument to '>' must be INTEGER, got %s", arrayArg.Elements[i].Type())
+				first := arrayArg.Elements[i]
+				if !isNumber(first) {
+					return newError("argument to '>' must be NUMBER, got %s", first.Type())
 				}
-				second, ok := arrayArg.Elements[i+1].(*object.Integer)
-				if !ok {
-					return newError("argument to '>' must be INTEGER, got %s", arrayArg.Elements[i+1].Type())
+				second := arrayArg.Elements[i+1]
+				if !isNumber(second) {
+					return newError("argument to '>' must be NUMBER, got %s", second.Type())
 				}
-				if first.Value <= second.Value {
+				if compareNumbers(first, second) <= 0 {
 					return False
 				}
 			}
@@ -203,15 +216,15 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for i := 0; i < len(arrayArg.Elements)-1; i++ {
-				first, ok := arrayArg.Elements[i].(*object.Integer)
-				if !ok {
-					return newError("argument to '<' must be INTEGER, got %s", arrayArg.Elements[i].Type())
+				first := arrayArg.Elements[i]
+				if !isNumber(first) {
+					return newError("argument to '<' must be NUMBER, got %s", first.Type())
 				}
-				second, ok := arrayArg.Elements[i+1].(*object.Integer)
-				if !ok {
-					return newError("argument to '<' must be INTEGER, got %s", arrayArg.Elements[i+1].Type())
+				second := arrayArg.Elements[i+1]
+				if !isNumber(second) {
+					return newError("argument to '<' must be NUMBER, got %s", second.Type())
 				}
-				if first.Value >= second.Value {
+				if compareNumbers(first, second) >= 0 {
 					return False
 				}
 			}
@@ -230,15 +243,15 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for i := 0; i < len(arrayArg.Elements)-1; i++ {
-				first, ok := arrayArg.Elements[i].(*object.Integer)
-				if !ok {
-					return newError("argument to '>=' must be INTEGER, got %s", arrayArg.Elements[i].Type())
+				first := arrayArg.Elements[i]
+				if !isNumber(first) {
+					return newError("argument to '>=' must be NUMBER, got %s", first.Type())
 				}
-				second, ok := arrayArg.Elements[i+1].(*object.Integer)
-				if !ok {
-					return newError("argument to '>=' must be INTEGER, got %s", arrayArg.Elements[i+1].Type())
+				second := arrayArg.Elements[i+1]
+				if !isNumber(second) {
+					return newError("argument to '>=' must be NUMBER, got %s", second.Type())
 				}
-				if first.Value < second.Value {
+				if compareNumbers(first, second) < 0 {
 					return False
 				}
 			}
@@ -257,7 +270,7 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for _, arg := range arrayArg.Elements {
-				if arg != True {
+				if !isTruthy(arg) {
 					return False
 				}
 			}
@@ -276,7 +289,7 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for _, arg := range arrayArg.Elements {
-				if arg == True {
+				if isTruthy(arg) {
 					return True
 				}
 			}
@@ -295,15 +308,15 @@ var builtins = map[string]*object.Builtin{
 			}
 
 			for i := 0; i < len(arrayArg.Elements)-1; i++ {
-				first, ok := arrayArg.Elements[i].(*object.Integer)
-				if !ok {
-					return newError("argument to '<=' must be INTEGER, got %s", arrayArg.Elements[i].Type())
+				first := arrayArg.Elements[i]
+				if !isNumber(first) {
+					return newError("argument to '<=' must be NUMBER, got %s", first.Type())
 				}
-				second, ok := arrayArg.Elements[i+1].(*object.Integer)
-				if !ok {
-					return newError("argument to '<=' must be INTEGER, got %s", arrayArg.Elements[i+1].Type())
+				second := arrayArg.Elements[i+1]
+				if !isNumber(second) {
+					return newError("argument to '<=' must be NUMBER, got %s", second.Type())
 				}
-				if first.Value > second.Value {
+				if compareNumbers(first, second) > 0 {
 					return False
 				}
 			}
@@ -313,15 +326,9 @@ var builtins = map[string]*object.Builtin{
 	},
 	"!": {
 		Fn: func(args object.Object) object.Object {
-			if args.Type() != object.BOOLEAN_OBJ {
-				return newError("argument to '!' must be BOOLEAN, got %s", args.Type())
-			}
-
-			if args == True {
-				return False
-			}
-			return True
+			return nativeBoolToBooleanObject(!isTruthy(args))
 		},
+		Params: []string{"$value"},
 	},
 	"at": {
 		Fn: func(args object.Object) object.Object {
@@ -333,9 +340,21 @@ var builtins = map[string]*object.Builtin{
 				return newError("number of arguments to 'at' must be 2, got %d", len(arrayArg.Elements))
 			}
 
+			if mapObj, ok := arrayArg.Elements[0].(*object.Map); ok {
+				key, ok := arrayArg.Elements[1].(*object.String)
+				if !ok {
+					return newError("second argument to 'at' must be STRING for MAP, got %s", arrayArg.Elements[1].Type())
+				}
+				value, ok := mapObj.Get(key.Value)
+				if !ok {
+					return newError("key not found: %s", key.Value)
+				}
+				return value
+			}
+
 			variable, ok := arrayArg.Elements[0].(*object.Array)
 			if !ok {
-				return newError("first argument to 'at' must be ARRAY, got %s", arrayArg.Elements[0].Type())
+				return newError("first argument to 'at' must be ARRAY or MAP, got %s", arrayArg.Elements[0].Type())
 			}
 
 			index, ok := arrayArg.Elements[1].(*object.Integer)
@@ -349,6 +368,7 @@ var builtins = map[string]*object.Builtin{
 
 			return variable.Elements[index.Value]
 		},
+		Params: []string{"$collection", "$index"},
 	},
 	"print": {
 		Fn: func(args object.Object) object.Object {
@@ -356,15 +376,19 @@ var builtins = map[string]*object.Builtin{
 
 			return Null
 		},
+		Params: []string{"$value"},
 	},
 	"len": {
 		Fn: func(args object.Object) object.Object {
-			array, ok := args.(*object.Array)
-			if !ok {
-				return newError("argument to 'len' must be ARRAY, got %s", args.Type())
+			switch args := args.(type) {
+			case *object.Array:
+				return &object.Integer{Value: int64(len(args.Elements))}
+			case *object.Map:
+				return &object.Integer{Value: int64(len(args.Keys))}
+			default:
+				return newError("argument to 'len' must be ARRAY or MAP, got %s", args.Type())
 			}
-
-			return &object.Integer{Value: int64(len(array.Elements))}
 		},
+		Params: []string{"$value"},
 	},
 }
@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+// allowEnv is whether programs are permitted to read environment variables.
+var allowEnv = false
+
+// SetAllowEnv changes whether env_get and env_list can read environment variables.
+func SetAllowEnv(allow bool) {
+	allowEnv = allow
+}
+
+var envBuiltins = map[string]*object.Builtin{
+	"env_get": {
+		Fn: func(args object.Object) object.Object {
+			if !allowEnv {
+				return newError("permission denied: 'env_get' requires --allow-env")
+			}
+			name, ok := args.(*object.String)
+			if !ok {
+				return newError("argument to 'env_get' must be STRING, got %s", args.Type())
+			}
+
+			value, ok := os.LookupEnv(name.Value)
+			if !ok {
+				return Null
+			}
+			return &object.String{Value: value}
+		},
+		Params: []string{"$name"},
+	},
+	"env_list": {
+		Fn: func(args object.Object) object.Object {
+			if !allowEnv {
+				return newError("permission denied: 'env_list' requires --allow-env")
+			}
+			if !hasNoArguments(args) {
+				return newError("'env_list' takes no arguments, got %s", args.Inspect())
+			}
+
+			environ := os.Environ()
+			sort.Strings(environ)
+
+			envMap := object.NewMap()
+			for _, kv := range environ {
+				name, value, _ := strings.Cut(kv, "=")
+				envMap.Set(name, &object.String{Value: value})
+			}
+			return envMap
+		},
+	},
+}
+
+func init() {
+	for name, builtin := range envBuiltins {
+		builtins[name] = builtin
+	}
+}
@@ -0,0 +1,83 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+func TestEnvBuiltins(t *testing.T) {
+	SetAllowEnv(true)
+	defer SetAllowEnv(false)
+	t.Setenv("JSOP_TEST_VALUE", "hello")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name:     "get environment variable",
+			input:    `{"command": {"symbol": "env_get", "args": "JSOP_TEST_VALUE"}}`,
+			expected: "hello",
+		},
+		{
+			name:     "get undefined environment variable",
+			input:    `{"command": {"symbol": "env_get", "args": "JSOP_TEST_UNDEFINED"}}`,
+			expected: nil,
+		},
+		{
+			name: "list environment variables",
+			input: `
+				{
+					"command": {
+						"symbol": "at",
+						"args": [
+							{
+								"command": {
+									"symbol": "env_list"
+								}
+							},
+							"JSOP_TEST_VALUE"
+						]
+					}
+				}`,
+			expected: "hello",
+		},
+		{
+			name:     "list environment variables with empty args",
+			input:    `{"command": {"symbol": "at", "args": [{"command": {"symbol": "env_list", "args": []}}, "JSOP_TEST_VALUE"]}}`,
+			expected: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			switch expected := tt.expected.(type) {
+			case string:
+				testStringObject(t, evaluated, expected)
+			case nil:
+				if evaluated != Null {
+					t.Errorf("object is not Null. got=%T (%+v)", evaluated, evaluated)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvBuiltinsNotAllowed(t *testing.T) {
+	t.Setenv("JSOP_TEST_VALUE", "hello")
+
+	input := `{"command": {"symbol": "env_get", "args": "JSOP_TEST_VALUE"}}`
+
+	evaluated := testEval(t, input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "permission denied: 'env_get' requires --allow-env"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
@@ -1,12 +1,15 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/JunNishimura/jsop/ast"
 	"github.com/JunNishimura/jsop/object"
+	"github.com/JunNishimura/jsop/token"
 )
 
 var (
@@ -20,11 +23,24 @@ var (
 const identEmbedPattern = `\{\s*\$\w+\s*\}`
 
 func Eval(exp ast.Expression, env *object.Environment) object.Object {
+	evaluated := evalExpression(exp, env)
+	// an error keeps the position of the innermost expression, since the outer ones only pass it through
+	if errObj, ok := evaluated.(*object.Error); ok && errObj.Pos.Line == 0 {
+		errObj.Pos = expressionPos(exp)
+	}
+	return evaluated
+}
+
+func evalExpression(exp ast.Expression, env *object.Environment) object.Object {
 	switch expt := exp.(type) {
 	case *ast.Array:
 		return evalArray(expt, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: expt.Value}
+	case *ast.BigIntegerLiteral:
+		return normalizeBigInt(expt.Value)
+	case *ast.FloatLiteral:
+		return &object.Float{Value: expt.Value}
 	case *ast.StringLiteral:
 		if strings.HasPrefix(expt.Value, "$") {
 			return evalSymbol(expt, env)
@@ -54,6 +70,31 @@ func Eval(exp ast.Expression, env *object.Environment) object.Object {
 	}
 }
 
+// EvalProgram evaluates the program and returns the value of each top-level expression,
+// that is, each element of the top-level array or the program itself.
+// evaluation stops at an error or a return, whose value is the last of the results.
+func EvalProgram(program ast.Expression, env *object.Environment) []object.Object {
+	if program == nil {
+		return []object.Object{}
+	}
+
+	array, ok := program.(*ast.Array)
+	if !ok {
+		return []object.Object{unwrapReturnValue(Eval(program, env))}
+	}
+
+	results := make([]object.Object, 0, len(array.Elements))
+	for _, el := range array.Elements {
+		evaluated := Eval(el, env)
+		results = append(results, unwrapReturnValue(evaluated))
+		if isError(evaluated) || evaluated.Type() == object.RETURN_VALUE_OBJ {
+			break
+		}
+	}
+
+	return results
+}
+
 func evalArray(array *ast.Array, env *object.Environment) object.Object {
 	result := &object.Array{
 		Elements: []object.Object{},
@@ -62,7 +103,7 @@ func evalArray(array *ast.Array, env *object.Environment) object.Object {
 	for _, el := range array.Elements {
 		evaluated := Eval(el, env)
 		if isError(evaluated) {
-			return &object.Array{Elements: []object.Object{evaluated}}
+			return evaluated
 		}
 		if returnValue, ok := evaluated.(*object.ReturnValue); ok {
 			return returnValue
@@ -77,6 +118,31 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// expressionPos returns the position of the expression in the source.
+// the line is 0 for the expressions made by macros, which have no position.
+func expressionPos(exp ast.Expression) token.Position {
+	switch expt := exp.(type) {
+	case *ast.IntegerLiteral:
+		return expt.Token.Pos
+	case *ast.BigIntegerLiteral:
+		return expt.Token.Pos
+	case *ast.FloatLiteral:
+		return expt.Token.Pos
+	case *ast.StringLiteral:
+		return expt.Token.Pos
+	case *ast.Boolean:
+		return expt.Token.Pos
+	case *ast.PrefixAtom:
+		return expt.Token.Pos
+	case *ast.Array:
+		return expt.Token.Pos
+	case *ast.KeyValueObject:
+		return expt.Token.Pos
+	default:
+		return token.Position{}
+	}
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -101,12 +167,14 @@ func evalPrefixAtom(operator string, right object.Object) object.Object {
 }
 
 func evalMinusPrefix(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer, *object.BigInt:
+		return evalIntegerArithmetic("-", &object.Integer{Value: 0}, right)
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right)
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalSymbol(symbol *ast.StringLiteral, env *object.Environment) object.Object {
@@ -123,19 +191,50 @@ func evalSymbol(symbol *ast.StringLiteral, env *object.Environment) object.Objec
 	return newError("symbol not found: %s", symbol.Value)
 }
 
+// specialFormKeys are the keys of objects which have meanings in jsop programs.
+var specialFormKeys = []string{
+	"command", "if", "cond", "switch", "match", "and", "or", "map", "set",
+	"loop", "lambda", "break", "continue", "return", "defmacro",
+}
+
+// IsSpecialForm reports whether the key of an object has a meaning in jsop programs.
+// the key is expected to be lowercased by the parser.
+func IsSpecialForm(key string) bool {
+	return slices.Contains(specialFormKeys, key)
+}
+
 func evalKeyValueObject(kv *ast.KeyValueObject, env *object.Environment) object.Object {
+	// an object of only comments does nothing
+	if kv.IsComment() {
+		return Null
+	}
+
 	for key, value := range kv.KVPairs() {
 		switch key {
 		case "command":
 			return evalCommandObject(value, env)
 		case "if":
 			return evalIfExpression(value, env)
+		case "cond":
+			return evalCondExpression(value, env)
+		case "switch":
+			return evalSwitchExpression(value, env)
+		case "match":
+			return evalMatchExpression(value, env)
+		case "and":
+			return evalAndExpression(value, env)
+		case "or":
+			return evalOrExpression(value, env)
+		case "map":
+			return evalMapExpression(value, env)
 		case "set":
 			return evalSetExpression(value, env)
 		case "loop":
 			return evalLoopExpression(value, env)
 		case "lambda":
 			return evalLambdaExpression(value, env)
+		case "defmacro":
+			return newError("defmacro must be an element of an array: %s", value)
 		case "break":
 			return Break
 		case "continue":
@@ -173,6 +272,13 @@ func evalCommandObject(exp ast.Expression, env *object.Environment) object.Objec
 			}
 			return quote(argsValue, env)
 		}
+		if symbolStr.Value == "macroexpand" || symbolStr.Value == "macroexpand-1" {
+			argsValue, ok := kvPairs["args"]
+			if !ok {
+				return newError("%s command requires args key: %s", symbolStr.Value, keyValueObj)
+			}
+			return macroexpand(symbolStr.Value, argsValue, env)
+		}
 
 		symbol = evalSymbol(symbolStr, env)
 		if isError(symbol) {
@@ -187,55 +293,266 @@ func evalCommandObject(exp ast.Expression, env *object.Environment) object.Objec
 
 	argsValue, ok := kvPairs["args"]
 	if !ok {
-		return applyFunction(symbol, Null)
+		return applyFunction(symbol, nil)
 	}
+
+	if argsObj, ok := argsValue.(*ast.KeyValueObject); ok && isNamedArguments(argsObj) {
+		named := object.NewMap()
+		for _, kv := range argsObj.KV {
+			evaluated := Eval(kv.Value, env)
+			if isError(evaluated) {
+				return evaluated
+			}
+			named.Set(mapKey(kv.Key), evaluated)
+		}
+		return applyFunctionWithNamedArgs(symbol, named)
+	}
+
+	// an array literal is the list of arguments, and any other value is a single argument
+	if argsArray, ok := argsValue.(*ast.Array); ok {
+		argList := evalArguments(argsArray, env)
+		if len(argList) == 1 && isError(argList[0]) {
+			return argList[0]
+		}
+		return applyFunction(symbol, argList)
+	}
+
 	args := Eval(argsValue, env)
 	if isError(args) {
 		return args
 	}
 
-	return applyFunction(symbol, args)
+	return applyFunction(symbol, []object.Object{args})
 }
 
-func extendFunctionEnv(fn *object.Function, args object.Object) (*object.Environment, error) {
-	switch args := args.(type) {
-	case *object.Array:
-		if len(fn.Parameters) != len(args.Elements) {
-			return nil, fmt.Errorf("wrong number of arguments. want=%d, got=%d", len(fn.Parameters), len(args.Elements))
+// isNamedArguments reports whether the args object is named arguments like {"$x": 1, "$y": 2}.
+// keys of the other objects such as map literal never start with $.
+func isNamedArguments(args *ast.KeyValueObject) bool {
+	if len(args.KV) == 0 {
+		return false
+	}
+	for _, kv := range args.KV {
+		if !strings.HasPrefix(kv.Key.Value, "$") {
+			return false
 		}
+	}
+	return true
+}
+
+// evalArguments evaluates the elements of the args array.
+// an element like "$...xs" is replaced with the elements of the array $xs.
+func evalArguments(args *ast.Array, env *object.Environment) []object.Object {
+	result := make([]object.Object, 0, len(args.Elements))
 
-		extendedEnv := object.NewEnclosedEnvironment(fn.Env)
-		for i, param := range fn.Parameters {
-			extendedEnv.Set(param.Value, args.Elements[i])
+	for _, el := range args.Elements {
+		if spread, ok := el.(*ast.StringLiteral); ok && strings.HasPrefix(spread.Value, restPrefix) {
+			symbol := &ast.StringLiteral{Token: spread.Token, Value: restVariable(spread.Value)}
+			evaluated := evalSymbol(symbol, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				return []object.Object{newError("spread argument must be ARRAY, got %s", evaluated.Type())}
+			}
+			result = append(result, array.Elements...)
+			continue
 		}
 
-		return extendedEnv, nil
-	case *object.Integer, *object.Boolean:
-		if len(fn.Parameters) != 1 {
-			return nil, fmt.Errorf("wrong number of arguments. want=%d, got=1", len(fn.Parameters))
+		evaluated := Eval(el, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
 		}
+		result = append(result, evaluated)
+	}
 
-		extendedEnv := object.NewEnclosedEnvironment(fn.Env)
-		extendedEnv.Set(fn.Parameters[0].Value, args)
+	return result
+}
 
-		return extendedEnv, nil
-	case *object.Null:
-		if len(fn.Parameters) != 0 {
-			return nil, fmt.Errorf("wrong number of arguments. want=%d, got=0", len(fn.Parameters))
+// extendFunctionEnv binds the arguments to the parameters.
+// named is nil unless the function is called with named arguments.
+func extendFunctionEnv(fn *object.Function, args []object.Object, named *object.Map) (*object.Environment, error) {
+	params := fn.Parameters
+	var rest *ast.StringLiteral
+	if len(params) > 0 && isRestParameter(params[len(params)-1]) {
+		rest = params[len(params)-1].(*ast.StringLiteral)
+		params = params[:len(params)-1]
+	}
+
+	required := 0
+	for _, param := range params {
+		if _, _, optional := splitParameter(param); !optional {
+			required++
+		}
+	}
+	if named == nil && (len(args) < required || (rest == nil && len(args) > len(params))) {
+		switch {
+		case rest != nil:
+			return nil, fmt.Errorf("wrong number of arguments. want at least %d, got=%d", required, len(args))
+		case required != len(params):
+			return nil, fmt.Errorf("wrong number of arguments. want=%d..%d, got=%d", required, len(params), len(args))
+		default:
+			return nil, fmt.Errorf("wrong number of arguments. want=%d, got=%d", len(params), len(args))
+		}
+	}
+
+	extendedEnv := object.NewEnclosedEnvironment(fn.Env)
+	bound := make(map[string]bool)
+	for i, param := range params {
+		pattern, defaultValue, optional := splitParameter(param)
+
+		var arg object.Object
+		isPassed := false
+		if i < len(args) {
+			arg, isPassed = args[i], true
+		} else if symbol, ok := pattern.(*ast.StringLiteral); ok && named != nil {
+			arg, isPassed = named.Get(symbol.Value)
+			bound[symbol.Value] = true
+		}
+
+		if !isPassed {
+			if !optional {
+				return nil, fmt.Errorf("missing argument for parameter %s", pattern)
+			}
+			arg = Null
+			if defaultValue != nil {
+				// default values are evaluated at each call, and can refer to the preceding parameters
+				arg = Eval(defaultValue, extendedEnv)
+				if errObj, ok := arg.(*object.Error); ok {
+					return nil, fmt.Errorf("fail to evaluate default value of %s: %s", pattern, errObj.Message)
+				}
+			}
+		}
+
+		if err := bindParameter(pattern, arg, extendedEnv); err != nil {
+			return nil, err
+		}
+	}
+
+	if rest != nil {
+		restArgs := make([]object.Object, 0)
+		if len(args) > len(params) {
+			restArgs = append(restArgs, args[len(params):]...)
+		}
+
+		var restArg object.Object = &object.Array{Elements: restArgs}
+		if named != nil {
+			if namedRest, ok := named.Get(restVariable(rest.Value)); ok {
+				if namedRest.Type() != object.ARRAY_OBJ {
+					return nil, fmt.Errorf("rest argument %s must be ARRAY, got %s", restVariable(rest.Value), namedRest.Type())
+				}
+				restArg = namedRest
+			}
+			bound[restVariable(rest.Value)] = true
+		}
+		extendedEnv.Define(restVariable(rest.Value), restArg)
+	}
+
+	if named != nil {
+		for _, key := range named.Keys {
+			if !bound[key] {
+				return nil, fmt.Errorf("unknown named argument: %s", key)
+			}
+		}
+	}
+
+	return extendedEnv, nil
+}
+
+// splitParameter returns the pattern and the default value of the parameter,
+// and reports whether the parameter is optional.
+// optional parameters are written as {"var": pattern, "default": value}.
+func splitParameter(param ast.Expression) (ast.Expression, ast.Expression, bool) {
+	kvObj, ok := param.(*ast.KeyValueObject)
+	if !ok {
+		return param, nil, false
+	}
+	kvPairs := kvObj.KVPairs()
+
+	pattern, ok := kvPairs["var"]
+	if !ok {
+		return param, nil, false
+	}
+
+	return pattern, kvPairs["default"], true
+}
+
+func isRestParameter(param ast.Expression) bool {
+	symbol, ok := param.(*ast.StringLiteral)
+	return ok && strings.HasPrefix(symbol.Value, restPrefix)
+}
+
+func bindParameter(param ast.Expression, arg object.Object, env *object.Environment) error {
+	matched, err := matchPattern(param, arg, env)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return fmt.Errorf("argument %s does not match parameter %s", arg.Inspect(), param)
+	}
+	return nil
+}
+
+// applyFunction calls the function with the list of arguments, which is nil when the command has no args.
+func applyFunction(function object.Object, argList []object.Object) object.Object {
+	switch funcType := function.(type) {
+	case *object.Builtin:
+		args, err := builtinArgs(funcType, argList)
+		if err != nil {
+			return newError("failed to apply function: %s", err)
 		}
+		return funcType.Fn(args)
+	case *object.Function:
+		extendedEnv, err := extendFunctionEnv(funcType, argList, nil)
+		if err != nil {
+			return newError("failed to apply function: %s", err)
+		}
+
+		evaluated := Eval(funcType.Body, extendedEnv)
+		return unwrapReturnValue(evaluated)
+	default:
+		return newError("not a function: %s", function.Type())
+	}
+}
+
+// builtinArgs converts the list of arguments into the value passed to the builtin function.
+// a builtin function with a single parameter receives the argument itself, and the others receive the list as an array.
+// Null is passed when the command has no args, or a builtin function with a single parameter gets no argument.
+func builtinArgs(builtin *object.Builtin, argList []object.Object) (object.Object, error) {
+	if argList == nil {
+		return Null, nil
+	}
+	if len(builtin.Params) != 1 {
+		return &object.Array{Elements: argList}, nil
+	}
 
-		return object.NewEnclosedEnvironment(fn.Env), nil
+	switch len(argList) {
+	case 0:
+		return Null, nil
+	case 1:
+		return argList[0], nil
 	default:
-		return nil, fmt.Errorf("unhandled argument type: %s", args.Type())
+		return nil, fmt.Errorf("wrong number of arguments. want=1, got=%d", len(argList))
 	}
 }
 
-func applyFunction(function object.Object, args object.Object) object.Object {
+// hasNoArguments reports whether a builtin function without parameters is called with no arguments,
+// that is, without args or with "args": [].
+func hasNoArguments(args object.Object) bool {
+	array, ok := args.(*object.Array)
+	return args == Null || (ok && len(array.Elements) == 0)
+}
+
+func applyFunctionWithNamedArgs(function object.Object, named *object.Map) object.Object {
 	switch funcType := function.(type) {
 	case *object.Builtin:
+		args, err := builtinNamedArgs(funcType, named)
+		if err != nil {
+			return newError("failed to apply function: %s", err)
+		}
 		return funcType.Fn(args)
 	case *object.Function:
-		extendedEnv, err := extendFunctionEnv(funcType, args)
+		extendedEnv, err := extendFunctionEnv(funcType, []object.Object{}, named)
 		if err != nil {
 			return newError("failed to apply function: %s", err)
 		}
@@ -247,6 +564,40 @@ func applyFunction(function object.Object, args object.Object) object.Object {
 	}
 }
 
+// builtinNamedArgs arranges the named arguments in the order of the parameters of the builtin function.
+// every parameter must be given except the optional ones at the end.
+func builtinNamedArgs(builtin *object.Builtin, named *object.Map) (object.Object, error) {
+	if len(builtin.Params) == 0 {
+		return nil, errors.New("builtin function does not accept named arguments")
+	}
+	for _, key := range named.Keys {
+		if !slices.Contains(builtin.Params, key) {
+			return nil, fmt.Errorf("unknown named argument: %s", key)
+		}
+	}
+
+	values := make([]object.Object, 0, len(builtin.Params))
+	for i, param := range builtin.Params {
+		value, ok := named.Get(param)
+		if !ok {
+			if i < len(builtin.Params)-builtin.OptionalParams {
+				return nil, fmt.Errorf("missing argument for parameter %s", param)
+			}
+			break
+		}
+		values = append(values, value)
+	}
+	// an optional parameter is given after the omitted one
+	if len(values) < len(named.Keys) {
+		return nil, fmt.Errorf("missing argument for parameter %s", builtin.Params[len(values)])
+	}
+
+	if len(builtin.Params) == 1 {
+		return values[0], nil
+	}
+	return &object.Array{Elements: values}, nil
+}
+
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
@@ -288,17 +639,174 @@ func evalIfExpression(exp ast.Expression, env *object.Environment) object.Object
 	return Eval(alternativeValue, env)
 }
 
+func evalCondExpression(exp ast.Expression, env *object.Environment) object.Object {
+	keyValueObj, ok := exp.(*ast.KeyValueObject)
+	if !ok {
+		return newError("invalid value for cond: %s", exp)
+	}
+	kvPairs := keyValueObj.KVPairs()
+
+	clausesValue, ok := kvPairs["clauses"]
+	if !ok {
+		return newError("clauses key not found in cond: %s", keyValueObj)
+	}
+	clauses, ok := clausesValue.(*ast.Array)
+	if !ok {
+		return newError("clauses key must be ARRAY, got %s", clausesValue)
+	}
+
+	for _, clause := range clauses.Elements {
+		clauseObj, ok := clause.(*ast.KeyValueObject)
+		if !ok {
+			return newError("clause in cond must be OBJECT, got %s", clause)
+		}
+		clausePairs := clauseObj.KVPairs()
+
+		whenValue, ok := clausePairs["when"]
+		if !ok {
+			return newError("when key not found in clause: %s", clauseObj)
+		}
+		thenValue, ok := clausePairs["then"]
+		if !ok {
+			return newError("then key not found in clause: %s", clauseObj)
+		}
+
+		condition := Eval(whenValue, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(thenValue, env)
+		}
+	}
+
+	defaultValue, ok := kvPairs["default"]
+	if !ok {
+		return Null
+	}
+
+	return Eval(defaultValue, env)
+}
+
+func evalSwitchExpression(exp ast.Expression, env *object.Environment) object.Object {
+	keyValueObj, ok := exp.(*ast.KeyValueObject)
+	if !ok {
+		return newError("invalid value for switch: %s", exp)
+	}
+	kvPairs := keyValueObj.KVPairs()
+
+	valueValue, ok := kvPairs["value"]
+	if !ok {
+		return newError("value key not found in switch: %s", keyValueObj)
+	}
+	value := Eval(valueValue, env)
+	if isError(value) {
+		return value
+	}
+
+	casesValue, ok := kvPairs["cases"]
+	if !ok {
+		return newError("cases key not found in switch: %s", keyValueObj)
+	}
+	cases, ok := casesValue.(*ast.Array)
+	if !ok {
+		return newError("cases key must be ARRAY, got %s", casesValue)
+	}
+
+	for _, c := range cases.Elements {
+		caseObj, ok := c.(*ast.KeyValueObject)
+		if !ok {
+			return newError("case in switch must be OBJECT, got %s", c)
+		}
+		casePairs := caseObj.KVPairs()
+
+		caseValue, ok := casePairs["case"]
+		if !ok {
+			return newError("case key not found in case: %s", caseObj)
+		}
+		thenValue, ok := casePairs["then"]
+		if !ok {
+			return newError("then key not found in case: %s", caseObj)
+		}
+
+		candidate := Eval(caseValue, env)
+		if isError(candidate) {
+			return candidate
+		}
+		if object.Equal(value, candidate) {
+			return Eval(thenValue, env)
+		}
+	}
+
+	defaultValue, ok := kvPairs["default"]
+	if !ok {
+		return Null
+	}
+
+	return Eval(defaultValue, env)
+}
+
+// isTruthy is the only rule to decide whether a value is regarded as true.
+// null, false, 0, 0.0, empty string, empty array and empty map are false, and the others are true.
 func isTruthy(obj object.Object) bool {
-	switch obj {
-	case True:
-		return true
-	case False:
+	switch obj := obj.(type) {
+	case *object.Null:
 		return false
+	case *object.Boolean:
+		return obj.Value
+	case *object.Integer:
+		return obj.Value != 0
+	case *object.Float:
+		return obj.Value != 0
+	case *object.String:
+		return obj.Value != ""
+	case *object.Array:
+		return len(obj.Elements) != 0
+	case *object.Map:
+		return len(obj.Keys) != 0
 	default:
 		return true
 	}
 }
 
+// evalAndExpression evaluates the operands from left to right, and stops at the first false operand.
+// it returns the operand that decides the result, or true if there is no operand.
+func evalAndExpression(exp ast.Expression, env *object.Environment) object.Object {
+	operands, ok := exp.(*ast.Array)
+	if !ok {
+		return newError("and key must be ARRAY, got %s", exp)
+	}
+
+	var result object.Object = True
+	for _, operand := range operands.Elements {
+		result = Eval(operand, env)
+		if isError(result) || !isTruthy(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
+// evalOrExpression evaluates the operands from left to right, and stops at the first true operand.
+// it returns the operand that decides the result, or false if there is no operand.
+func evalOrExpression(exp ast.Expression, env *object.Environment) object.Object {
+	operands, ok := exp.(*ast.Array)
+	if !ok {
+		return newError("or key must be ARRAY, got %s", exp)
+	}
+
+	var result object.Object = False
+	for _, operand := range operands.Elements {
+		result = Eval(operand, env)
+		if isError(result) || isTruthy(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
 func evalSetExpression(exp ast.Expression, env *object.Environment) object.Object {
 	keyValueObj, ok := exp.(*ast.KeyValueObject)
 	if !ok {
@@ -310,12 +818,14 @@ func evalSetExpression(exp ast.Expression, env *object.Environment) object.Objec
 	if !ok {
 		return newError("var key not found in set: %s", keyValueObj)
 	}
-	variable, ok := varValue.(*ast.StringLiteral)
-	if !ok {
-		return newError("var key must be SYMBOL, got %s", varValue)
-	}
-	if !strings.HasPrefix(variable.Value, "$") {
-		return newError("var key must not start with $: %s", variable.Value)
+	switch variable := varValue.(type) {
+	case *ast.Array, *ast.KeyValueObject:
+	case *ast.StringLiteral:
+		if !strings.HasPrefix(variable.Value, "$") {
+			return newError("var key must not start with $: %s", variable.Value)
+		}
+	default:
+		return newError("var key must be SYMBOL or pattern, got %s", varValue)
 	}
 
 	valueValue, ok := kvPairs["val"]
@@ -327,7 +837,37 @@ func evalSetExpression(exp ast.Expression, env *object.Environment) object.Objec
 		return value
 	}
 
-	return env.Set(variable.Value, value)
+	if variable, ok := varValue.(*ast.StringLiteral); ok {
+		return env.Set(variable.Value, value)
+	}
+
+	return setPattern(varValue, value, env)
+}
+
+// setPattern destructures the value by the pattern and assigns
+// the bound variables in the same way as a plain set.
+func setPattern(pattern ast.Expression, value object.Object, env *object.Environment) object.Object {
+	variables, err := patternVariables(pattern)
+	if err != nil {
+		return newError("invalid pattern for var key %s: %s", pattern, err)
+	}
+
+	bindings := object.NewEnvironment()
+	matched, err := matchPattern(pattern, value, bindings)
+	if err != nil {
+		return newError("invalid pattern for var key %s: %s", pattern, err)
+	}
+	if !matched {
+		return newError("value %s does not match pattern %s", value.Inspect(), pattern)
+	}
+
+	for _, variable := range variables {
+		if bound, ok := bindings.Get(variable); ok {
+			env.Set(variable, bound)
+		}
+	}
+
+	return value
 }
 
 func evalLoopExpression(exp ast.Expression, env *object.Environment) object.Object {
@@ -515,30 +1055,41 @@ func evalLambdaExpression(exp ast.Expression, env *object.Environment) object.Ob
 	}
 	kvPairs := keyValueObj.KVPairs()
 
-	params := make([]*ast.StringLiteral, 0)
+	params := make([]ast.Expression, 0)
 	paramsValue, ok := kvPairs["params"]
 	if ok {
 		if paramsArray, ok := paramsValue.(*ast.Array); ok {
-			for _, param := range paramsArray.Elements {
-				paramSymbol, ok := param.(*ast.StringLiteral)
-				if !ok {
-					return newError("params key must be ARRAY of SYMBOL, got %s", param)
-				}
-				if !strings.HasPrefix(paramSymbol.Value, "$") {
-					return newError("params key must not start with $: %s", paramSymbol.Value)
-				}
-				params = append(params, paramSymbol)
-			}
+			params = append(params, paramsArray.Elements...)
 		} else {
-			paramsSymbol, ok := paramsValue.(*ast.StringLiteral)
-			if !ok {
-				return newError("params key must be ARRAY or SYMBOL, got %s", paramsValue)
-			}
-			if !strings.HasPrefix(paramsSymbol.Value, "$") {
-				return newError("params key must not start with $: %s", paramsSymbol.Value)
+			params = append(params, paramsValue)
+		}
+	}
+	patterns := make([]ast.Expression, 0, len(params))
+	hasOptional := false
+	for i, param := range params {
+		pattern, _, optional := splitParameter(param)
+		if optional {
+			hasOptional = true
+		} else if hasOptional && !isRestParameter(param) {
+			return newError("required parameter must not follow optional parameter: %s", param)
+		}
+		if isRestParameter(param) && i != len(params)-1 {
+			return newError("rest parameter must be the last parameter: %s", param)
+		}
+
+		switch pattern := pattern.(type) {
+		case *ast.Array, *ast.KeyValueObject:
+		case *ast.StringLiteral:
+			if !strings.HasPrefix(pattern.Value, "$") {
+				return newError("params key must not start with $: %s", pattern.Value)
 			}
-			params = append(params, paramsSymbol)
+		default:
+			return newError("params key must be ARRAY of SYMBOL or pattern, got %s", pattern)
 		}
+		patterns = append(patterns, pattern)
+	}
+	if _, err := patternVariables(&ast.Array{Elements: patterns}); err != nil {
+		return newError("invalid params %s: %s", paramsValue, err)
 	}
 
 	body, ok := kvPairs["body"]
@@ -553,6 +1104,30 @@ func evalLambdaExpression(exp ast.Expression, env *object.Environment) object.Ob
 	}
 }
 
+func evalMapExpression(exp ast.Expression, env *object.Environment) object.Object {
+	keyValueObj, ok := exp.(*ast.KeyValueObject)
+	if !ok {
+		return newError("invalid value for map: %s", exp)
+	}
+
+	mapObj := object.NewMap()
+	for _, kv := range keyValueObj.KV {
+		value := Eval(kv.Value, env)
+		if isError(value) {
+			return value
+		}
+		mapObj.Set(mapKey(kv.Key), value)
+	}
+
+	return mapObj
+}
+
+// mapKey returns the key as it is written in the program,
+// since the parser converts keys to lower case.
+func mapKey(key *ast.StringLiteral) string {
+	return key.Token.Literal
+}
+
 func evalEmbeddedIdentifiers(strLiteral *ast.StringLiteral, env *object.Environment, matches []string) object.Object {
 	evaluatedIdents := make([]object.Object, 0)
 	for _, match := range matches {
@@ -1,11 +1,13 @@
 package evaluator
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/JunNishimura/jsop/lexer"
 	"github.com/JunNishimura/jsop/object"
 	"github.com/JunNishimura/jsop/parser"
+	"github.com/JunNishimura/jsop/token"
 )
 
 func testEval(t *testing.T, input string) object.Object {
@@ -21,6 +23,163 @@ func testEval(t *testing.T, input string) object.Object {
 	return Eval(program, env)
 }
 
+func TestEvalProgram(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []any
+	}{
+		{
+			name:     "empty program",
+			input:    "",
+			expected: []any{},
+		},
+		{
+			name:     "empty array",
+			input:    "[]",
+			expected: []any{},
+		},
+		{
+			name:     "single expression",
+			input:    `{"command": {"symbol": "+", "args": [1, 2]}}`,
+			expected: []any{3},
+		},
+		{
+			name: "each top-level expression",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$x",
+							"val": 1
+						}
+					},
+					"x is {$x}",
+					[1, 2]
+				]`,
+			expected: []any{1, "x is 1", []any{1, 2}},
+		},
+		{
+			name: "stop at return",
+			input: `
+				[
+					1,
+					{
+						"return": 2
+					},
+					3
+				]`,
+			expected: []any{1, 2},
+		},
+		{
+			name: "skip comments",
+			input: `
+				[
+					{"//": "comment only element"},
+					{
+						"//": "comment before the key",
+						"set": {
+							"var": "$x",
+							"val": 1
+						}
+					},
+					{"command": {"symbol": "+", "args": ["$x", {"//": "not an argument"}, 2]}},
+					{"//": "last comment"}
+				]`,
+			expected: []any{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(tt.input)
+			p := parser.New(l)
+			program, err := p.ParseProgram()
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+
+			results := EvalProgram(program, object.NewEnvironment())
+			testArrayObject(t, &object.Array{Elements: results}, tt.expected)
+		})
+	}
+}
+
+func TestEvalProgramError(t *testing.T) {
+	input := `
+		[
+			1,
+			{
+				"command": {
+					"symbol": "/",
+					"args": [1, 0]
+				}
+			},
+			3
+		]`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	results := EvalProgram(program, object.NewEnvironment())
+	if len(results) != 2 {
+		t.Fatalf("wrong number of results. got=%d, want=2", len(results))
+	}
+	testIntegerObject(t, results[0], 1)
+	errObj, ok := results[1].(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", results[1], results[1])
+	}
+	if errObj.Message != "division by zero" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected token.Position
+	}{
+		{
+			name:     "error of builtin function",
+			input:    `[1, {"command": {"symbol": "/", "args": [1, 0]}}]`,
+			expected: token.Position{Line: 1, Column: 5},
+		},
+		{
+			name:     "error of argument",
+			input:    `{"command": {"symbol": "+", "args": [1, "$x"]}}`,
+			expected: token.Position{Line: 1, Column: 42},
+		},
+		{
+			name: "error in body of lambda",
+			input: `
+[
+	{"set": {"var": "$f", "val": {"lambda": {"params": "$x", "body": {"command": {"symbol": "len", "args": "$x"}}}}}},
+	{"command": {"symbol": "$f", "args": 1}}
+]`,
+			expected: token.Position{Line: 3, Column: 67},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Pos != tt.expected {
+				t.Errorf("wrong error position. expected=%+v, got=%+v", tt.expected, errObj.Pos)
+			}
+		})
+	}
+}
+
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) {
 	result, ok := obj.(*object.Integer)
 	if !ok {
@@ -134,6 +293,183 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func testBigIntObject(t *testing.T, obj object.Object, expected string) {
+	result, ok := obj.(*object.BigInt)
+	if !ok {
+		t.Fatalf("object is not BigInt. got=%T (%+v)", obj, obj)
+	}
+
+	if result.Value.String() != expected {
+		t.Fatalf("object has wrong value. got=%s, want=%s", result.Value.String(), expected)
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "huge integer literal",
+			input:    "123456789012345678901234567890",
+			expected: "123456789012345678901234567890",
+		},
+		{
+			name:     "negative huge integer literal",
+			input:    "-123456789012345678901234567890",
+			expected: "-123456789012345678901234567890",
+		},
+		{
+			name: "addition overflow",
+			input: `
+				{
+					"command": {
+						"symbol": "+",
+						"args": [9223372036854775807, 1]
+					}
+				}`,
+			expected: "9223372036854775808",
+		},
+		{
+			name: "subtraction overflow",
+			input: `
+				{
+					"command": {
+						"symbol": "-",
+						"args": [-9223372036854775807, 2]
+					}
+				}`,
+			expected: "-9223372036854775809",
+		},
+		{
+			name: "multiplication overflow",
+			input: `
+				{
+					"command": {
+						"symbol": "*",
+						"args": [4294967296, 4294967296]
+					}
+				}`,
+			expected: "18446744073709551616",
+		},
+		{
+			name: "division overflow",
+			input: `
+				{
+					"command": {
+						"symbol": "/",
+						"args": [-9223372036854775808, -1]
+					}
+				}`,
+			expected: "9223372036854775808",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testBigIntObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestBigIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name: "result that fits in int64 becomes integer",
+			input: `
+				{
+					"command": {
+						"symbol": "-",
+						"args": [9223372036854775808, 1]
+					}
+				}`,
+			expected: int64(9223372036854775807),
+		},
+		{
+			name: "modulo of big integer",
+			input: `
+				{
+					"command": {
+						"symbol": "%",
+						"args": [100000000000000000000, 7]
+					}
+				}`,
+			expected: int64(2),
+		},
+		{
+			name: "compare big integer",
+			input: `
+				{
+					"command": {
+						"symbol": "<",
+						"args": [1, 9223372036854775808, 100000000000000000000]
+					}
+				}`,
+			expected: true,
+		},
+		{
+			name: "equality of big integer",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": [
+							{
+								"command": {
+									"symbol": "+",
+									"args": [9223372036854775807, 1]
+								}
+							},
+							9223372036854775808
+						]
+					}
+				}`,
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			switch expected := tt.expected.(type) {
+			case int64:
+				testIntegerObject(t, evaluated, expected)
+			case bool:
+				testBooleanObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestIntegerOverflowError(t *testing.T) {
+	SetOverflowMode(OverflowError)
+	defer SetOverflowMode(OverflowPromote)
+
+	input := `
+		{
+			"command": {
+				"symbol": "+",
+				"args": [9223372036854775807, 1]
+			}
+		}`
+
+	evaluated := testEval(t, input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "integer overflow: 9223372036854775807 + 1"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -315,6 +651,17 @@ func TestEvalBooleanExpression(t *testing.T) {
 				}`,
 			expected: true,
 		},
+		{
+			name: "negation of false in argument list",
+			input: `
+				{
+					"command": {
+						"symbol": "!",
+						"args": [false]
+					}
+				}`,
+			expected: true,
+		},
 		{
 			name: "logical AND: return true",
 			input: `
@@ -370,41 +717,162 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
-func testStringObject(t *testing.T, obj object.Object, expected string) {
-	result, ok := obj.(*object.String)
-	if !ok {
-		t.Fatalf("object is not String. got=%T", obj)
-	}
-
-	if result.Value != expected {
-		t.Fatalf("object has wrong value. got=%s, want=%s", result.Value, expected)
-	}
-}
-
-func TestEvalStringExpression(t *testing.T) {
+func TestEquality(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
-		expected string
+		expected bool
 	}{
 		{
-			name:     "string literal",
-			input:    `"hello"`,
-			expected: "hello",
+			name: "string and integer are not equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": ["1", 1]
+					}
+				}`,
+			expected: false,
 		},
 		{
-			name:     "string with space",
-			input:    `"hello world"`,
-			expected: "hello world",
+			name: "string and boolean are not equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": ["true", true]
+					}
+				}`,
+			expected: false,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			evaluated := testEval(t, tt.input)
-			testStringObject(t, evaluated, tt.expected)
-		})
-	}
+		{
+			name: "string and integer are different",
+			input: `
+				{
+					"command": {
+						"symbol": "!=",
+						"args": ["1", 1]
+					}
+				}`,
+			expected: true,
+		},
+		{
+			name: "nested arrays are equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": [[1, ["a", true]], [1, ["a", true]]]
+					}
+				}`,
+			expected: true,
+		},
+		{
+			name: "arrays containing different types are not equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": [[1, 2], [1, "2"]]
+					}
+				}`,
+			expected: false,
+		},
+		{
+			name: "maps with the same pairs in different order are equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": [
+							{"map": {"a": 1, "b": [2]}},
+							{"map": {"b": [2], "a": 1}}
+						]
+					}
+				}`,
+			expected: true,
+		},
+		{
+			name: "distinct lambdas with the same body are not equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": [
+							{"lambda": {"params": "$x", "body": "$x"}},
+							{"lambda": {"params": "$x", "body": "$x"}}
+						]
+					}
+				}`,
+			expected: false,
+		},
+		{
+			name: "lambda is equal to itself",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {"lambda": {"params": "$x", "body": "$x"}}
+						}
+					},
+					{
+						"command": {
+							"symbol": "==",
+							"args": ["$f", "$f"]
+						}
+					}
+				]`,
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			if evaluatedArray, ok := evaluated.(*object.Array); ok {
+				evaluated = evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
+			}
+			testBooleanObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T", obj)
+	}
+
+	if result.Value != expected {
+		t.Fatalf("object has wrong value. got=%s, want=%s", result.Value, expected)
+	}
+}
+
+func TestEvalStringExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "string literal",
+			input:    `"hello"`,
+			expected: "hello",
+		},
+		{
+			name:     "string with space",
+			input:    `"hello world"`,
+			expected: "hello world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testStringObject(t, evaluated, tt.expected)
+		})
+	}
 }
 
 func TestIfElseExpression(t *testing.T) {
@@ -473,226 +941,1285 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
-func TestLoopExpression(t *testing.T) {
+func TestTruthiness(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
-		expected int64
+		expected bool
+	}{
+		{name: "null", input: `{"command": {"symbol": "print"}}`, expected: false},
+		{name: "false", input: "false", expected: false},
+		{name: "zero", input: "0", expected: false},
+		{name: "empty string", input: `""`, expected: false},
+		{name: "empty array", input: "[]", expected: false},
+		{name: "empty map", input: `{"map": {}}`, expected: false},
+		{name: "true", input: "true", expected: true},
+		{name: "non-zero integer", input: "-1", expected: true},
+		{name: "non-empty string", input: `"false"`, expected: true},
+		{name: "non-empty array", input: "[0]", expected: true},
+		{name: "lambda", input: `{"lambda": {"body": 1}}`, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ifInput := fmt.Sprintf(`{"if": {"cond": %s, "conseq": true, "alt": false}}`, tt.input)
+			testBooleanObject(t, testEval(t, ifInput), tt.expected)
+
+			notInput := fmt.Sprintf(`{"command": {"symbol": "!", "args": [%s]}}`, tt.input)
+			testBooleanObject(t, testEval(t, notInput), !tt.expected)
+		})
+	}
+}
+
+func TestAndOrExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
 	}{
 		{
-			name: "loop expression",
-			input: `
-				[
-					{
-						"loop": {
-							"for": "$i",
-							"from": 1,
-							"until": 3,
-							"do": {
-								"command": {
-									"symbol": "+",
-									"args": [1, "$i"]
-								}
-							}
-						}
-					}
-				]`,
-			expected: 3,
+			name:     "and returns the last operand when all operands are true",
+			input:    `{"and": [1, "a", [1]]}`,
+			expected: []any{1},
 		},
 		{
-			name: "loop expression with in keyword",
-			input: `
-				[
-					{
-						"set": {
-							"var": "$arr",
-							"val": [10, 20, 30]	
-						}
-					},
-					{
-						"set": {
-							"var": "$sum",
-							"val": 0
-						}
-					},
-					{
-						"loop": {
-							"for": "$element",
-							"in": "$arr",
-							"do": {
-								"set": {
-									"var": "$sum",
-									"val": {
-										"command": {
-											"symbol": "+",
-											"args": ["$sum", "$element"]
-										}
-									}
-								}
-							}
-						}
-					}
-				]`,
-			expected: 60,
+			name:     "and returns the first false operand",
+			input:    `{"and": [1, 0, "$undefined"]}`,
+			expected: 0,
 		},
 		{
-			name: "break and continue in loop",
+			name:     "and without operands",
+			input:    `{"and": []}`,
+			expected: true,
+		},
+		{
+			name:     "or returns the first true operand",
+			input:    `{"or": [0, "", "found", "$undefined"]}`,
+			expected: "found",
+		},
+		{
+			name:     "or returns the last operand when all operands are false",
+			input:    `{"or": [false, 0, ""]}`,
+			expected: "",
+		},
+		{
+			name:     "or without operands",
+			input:    `{"or": []}`,
+			expected: false,
+		},
+		{
+			name: "guard clause",
 			input: `
 				[
 					{
 						"set": {
-							"var": "$sum",
-							"val": 0
+							"var": "$items",
+							"val": []
 						}
 					},
 					{
-						"loop": {
-							"for": "$i",
-							"from": 1,
-							"until": 15,
-							"do": {
-								"if": {
-									"cond": {
-										"command": {
-											"symbol": ">",
-											"args": ["$i", 10]
-										}
-									},
-									"conseq": {
-										"break": {}
-									},
-									"alt": {
-										"if": {
-											"cond": {
-												"command": {
-													"symbol": "==",
-													"args": [
-														{
-															"command": {
-																"symbol": "%",
-																"args": ["$i", 2]
-															}
-														},
-														0
-													]
-												}
-											},
-											"conseq": {
-												"set": {
-													"var": "$sum",
-													"val": {
-														"command": {
-															"symbol": "+",
-															"args": ["$sum", "$i"]
-														}
-													}
-												}
-											},
-											"alt": {
-												"continue": {}
+						"and": [
+							"$items",
+							{
+								"command": {
+									"symbol": ">",
+									"args": [
+										{
+											"command": {
+												"symbol": "at",
+												"args": ["$items", 0]
 											}
-										}
-									}
+										},
+										0
+									]
 								}
 							}
-						}
-					},
-					"$sum"
+						]
+					}
 				]`,
-			expected: 30,
+			expected: []any{[]any{}, []any{}},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			evaluated := testEval(t, tt.input)
-
-			evaluatedArray, ok := evaluated.(*object.Array)
-			if !ok {
-				t.Fatalf("object is not Array. got=%T", evaluated)
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case bool:
+				testBooleanObject(t, evaluated, expected)
+			case string:
+				testStringObject(t, evaluated, expected)
+			case []any:
+				testArrayObject(t, evaluated, expected)
 			}
-
-			finalResult := evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
-			testIntegerObject(t, finalResult, tt.expected)
 		})
 	}
 }
 
-func TestSetExpression(t *testing.T) {
+func TestCondExpression(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
 		expected int64
 	}{
 		{
-			name: "set expression",
+			name: "first matching clause is evaluated",
 			input: `
 				{
-					"set": {
-						"var": "$x",
-						"val": 10
-					}
-				}`,
+					"cond": {
+						"clauses": [
+							{
+								"when": false,
+								"then": 1
+							},
+							{
+								"when": {
+									"command": {
+										"symbol": ">",
+										"args": [2, 1]
+									}
+								},
+								"then": 2
+							},
+							{
+								"when": true,
+								"then": 3
+							}
+						],
+						"default": 4
+					}
+				}`,
+			expected: 2,
+		},
+		{
+			name: "default is evaluated when no clause matches",
+			input: `
+				{
+					"cond": {
+						"clauses": [
+							{
+								"when": false,
+								"then": 1
+							}
+						],
+						"default": {
+							"command": {
+								"symbol": "+",
+								"args": [2, 3]
+							}
+						}
+					}
+				}`,
+			expected: 5,
+		},
+		{
+			name: "clauses after the matching one are not evaluated",
+			input: `
+				{
+					"cond": {
+						"clauses": [
+							{
+								"when": true,
+								"then": 1
+							},
+							{
+								"when": "$undefined",
+								"then": "$undefined"
+							}
+						]
+					}
+				}`,
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestSwitchExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{
+			name: "switch on integer",
+			input: `
+				{
+					"switch": {
+						"value": {
+							"command": {
+								"symbol": "%",
+								"args": [7, 3]
+							}
+						},
+						"cases": [
+							{
+								"case": 0,
+								"then": 10
+							},
+							{
+								"case": 1,
+								"then": 20
+							}
+						],
+						"default": 30
+					}
+				}`,
+			expected: 20,
+		},
+		{
+			name: "switch on array",
+			input: `
+				{
+					"switch": {
+						"value": [1, [2, 3]],
+						"cases": [
+							{
+								"case": [1, 2, 3],
+								"then": 10
+							},
+							{
+								"case": [1, [2, 3]],
+								"then": 20
+							}
+						]
+					}
+				}`,
+			expected: 20,
+		},
+		{
+			name: "values of different types do not match",
+			input: `
+				{
+					"switch": {
+						"value": "1",
+						"cases": [
+							{
+								"case": 1,
+								"then": 10
+							}
+						],
+						"default": 20
+					}
+				}`,
+			expected: 20,
+		},
+		{
+			name: "cases after the matching one are not evaluated",
+			input: `
+				{
+					"switch": {
+						"value": true,
+						"cases": [
+							{
+								"case": true,
+								"then": 10
+							},
+							{
+								"case": "$undefined",
+								"then": "$undefined"
+							}
+						]
+					}
+				}`,
+			expected: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestLoopExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{
+			name: "loop expression",
+			input: `
+				[
+					{
+						"loop": {
+							"for": "$i",
+							"from": 1,
+							"until": 3,
+							"do": {
+								"command": {
+									"symbol": "+",
+									"args": [1, "$i"]
+								}
+							}
+						}
+					}
+				]`,
+			expected: 3,
+		},
+		{
+			name: "loop expression with in keyword",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$arr",
+							"val": [10, 20, 30]	
+						}
+					},
+					{
+						"set": {
+							"var": "$sum",
+							"val": 0
+						}
+					},
+					{
+						"loop": {
+							"for": "$element",
+							"in": "$arr",
+							"do": {
+								"set": {
+									"var": "$sum",
+									"val": {
+										"command": {
+											"symbol": "+",
+											"args": ["$sum", "$element"]
+										}
+									}
+								}
+							}
+						}
+					}
+				]`,
+			expected: 60,
+		},
+		{
+			name: "break and continue in loop",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$sum",
+							"val": 0
+						}
+					},
+					{
+						"loop": {
+							"for": "$i",
+							"from": 1,
+							"until": 15,
+							"do": {
+								"if": {
+									"cond": {
+										"command": {
+											"symbol": ">",
+											"args": ["$i", 10]
+										}
+									},
+									"conseq": {
+										"break": {}
+									},
+									"alt": {
+										"if": {
+											"cond": {
+												"command": {
+													"symbol": "==",
+													"args": [
+														{
+															"command": {
+																"symbol": "%",
+																"args": ["$i", 2]
+															}
+														},
+														0
+													]
+												}
+											},
+											"conseq": {
+												"set": {
+													"var": "$sum",
+													"val": {
+														"command": {
+															"symbol": "+",
+															"args": ["$sum", "$i"]
+														}
+													}
+												}
+											},
+											"alt": {
+												"continue": {}
+											}
+										}
+									}
+								}
+							}
+						}
+					},
+					"$sum"
+				]`,
+			expected: 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+
+			evaluatedArray, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Fatalf("object is not Array. got=%T", evaluated)
+			}
+
+			finalResult := evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
+			testIntegerObject(t, finalResult, tt.expected)
+		})
+	}
+}
+
+func TestSetExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{
+			name: "set expression",
+			input: `
+				{
+					"set": {
+						"var": "$x",
+						"val": 10
+					}
+				}`,
 			expected: 10,
 		},
 		{
-			name: "set expression with object",
-			input: `
-				{
-					"set": {
-						"var": "$x",
-						"val": {
-							"command": {
-								"symbol": "+",
-								"args": [1, 2]
-							}
-						}
-					}
-				}`,
-			expected: 3,
+			name: "set expression with object",
+			input: `
+				{
+					"set": {
+						"var": "$x",
+						"val": {
+							"command": {
+								"symbol": "+",
+								"args": [1, 2]
+							}
+						}
+					}
+				}`,
+			expected: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestDestructuringSetExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []any
+	}{
+		{
+			name: "unpack array",
+			input: `
+				[
+					{
+						"set": {
+							"var": ["$a", "$b"],
+							"val": [1, 2]
+						}
+					},
+					"$a",
+					"$b"
+				]`,
+			expected: []any{[]any{1, 2}, 1, 2},
+		},
+		{
+			name: "unpack values returned from function",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$divmod",
+							"val": {
+								"lambda": {
+									"params": ["$x", "$y"],
+									"body": [
+										{
+											"command": {
+												"symbol": "/",
+												"args": ["$x", "$y"]
+											}
+										},
+										{
+											"command": {
+												"symbol": "%",
+												"args": ["$x", "$y"]
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					{
+						"set": {
+							"var": ["$q", "$r"],
+							"val": {
+								"command": {
+									"symbol": "$divmod",
+									"args": [7, 2]
+								}
+							}
+						}
+					},
+					"$q",
+					"$r"
+				]`,
+			expected: []any{nil, []any{3, 1}, 3, 1},
+		},
+		{
+			name: "unpack nested array and map with rest",
+			input: `
+				[
+					{
+						"set": {
+							"var": {
+								"map": {
+									"point": ["$x", "$...others"],
+									"label": "$label"
+								}
+							},
+							"val": {
+								"map": {
+									"point": [1, 2, 3],
+									"label": "p"
+								}
+							}
+						}
+					},
+					"$x",
+					"$others",
+					"$label"
+				]`,
+			expected: []any{nil, 1, []any{2, 3}, "p"},
+		},
+		{
+			name: "update existing variable",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$a",
+							"val": 1
+						}
+					},
+					{
+						"set": {
+							"var": ["$a", "$_"],
+							"val": [10, 20]
+						}
+					},
+					"$a"
+				]`,
+			expected: []any{1, []any{10, 20}, 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testArrayObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestDestructuringSetExpressionError(t *testing.T) {
+	input := `
+		{
+			"set": {
+				"var": ["$a", "$b"],
+				"val": [1, 2, 3]
+			}
+		}`
+
+	evaluated := testEval(t, input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "value [1, 2, 3] does not match pattern [\"$a\", \"$b\"]"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestLambdaExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{
+			name: "call lambda expression directly",
+			input: `
+				[
+					{
+						"command": {
+							"symbol": {
+								"lambda": {
+									"params": "$x",
+									"body": {
+										"command": {
+											"symbol": "+",
+											"args": [1, "$x"]
+										}
+									}
+								}
+							},
+							"args": 10
+						}
+					}
+				]
+			`,
+			expected: 11,
+		},
+		{
+			name: "lambda expression without parameters",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"body": 10
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$f"
+						}
+					}
+				]`,
+			expected: 10,
+		},
+		{
+			name: "lambda expression with a parameter",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$addOne",
+							"val": {
+								"lambda": {
+									"params": "$x",
+									"body": {
+										"command": {
+											"symbol": "+",
+											"args": [1, "$x"]
+										}
+									}
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$addOne",
+							"args": 10
+						}
+					}
+				]`,
+			expected: 11,
+		},
+		{
+			name: "lambda expression with multiple parameters",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$addAndDouble",
+							"val": {
+								"lambda": {
+									"params": ["$x", "$y"],
+									"body": {
+										"command": {
+											"symbol": "*",
+											"args": [
+												{
+													"command": {
+														"symbol": "+",
+														"args": ["$x", "$y"]
+													}
+												},
+												2
+											]
+										}
+									}
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$addAndDouble",
+							"args": [1, 2]
+						}
+					}
+				]`,
+			expected: 6,
+		},
+		{
+			name: "lambda expression with destructuring parameters",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"params": [["$a", "$b"], {"map": {"c": "$c"}}],
+									"body": {
+										"command": {
+											"symbol": "+",
+											"args": ["$a", "$b", "$c"]
+										}
+									}
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$f",
+							"args": [[1, 2], {"map": {"c": 3}}]
+						}
+					}
+				]`,
+			expected: 6,
+		},
+		{
+			name: "parameters do not overwrite outer variables",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$x",
+							"val": 1
+						}
+					},
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"params": "$x",
+									"body": "$x"
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$f",
+							"args": 10
+						}
+					},
+					"$x"
+				]`,
+			expected: 1,
+		},
+		{
+			name: "lambda expression with return statement",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"body": [
+										{
+											"set": {
+												"var": "$sum",
+												"val": 0
+											}
+										},
+										{
+											"loop": {
+												"for": "$i",
+												"from": 1,
+												"until": 11,
+												"do": {
+													"if": {
+														"cond": {
+															"command": {
+																"symbol": ">",
+																"args": ["$i", 5]
+															}
+														},
+														"conseq": {
+															"return": "$sum"
+														},
+														"alt": {
+															"set": {
+																"var": "$sum",
+																"val": {
+																	"command": {
+																		"symbol": "+",
+																		"args": ["$sum", "$i"]
+																	}
+																}
+															}
+														}
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$f"
+						}
+					}
+				]`,
+			expected: 15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			evaluatedArray, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Fatalf("object is not Array. got=%T", evaluated)
+			}
+			finalResult := evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
+			testIntegerObject(t, finalResult, tt.expected)
+		})
+	}
+}
+
+func TestLambdaParameters(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name: "rest parameter",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"params": ["$first", "$...rest"],
+									"body": ["$first", "$rest"]
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$f",
+							"args": [1, 2, 3]
+						}
+					}
+				]`,
+			expected: []any{1, []any{2, 3}},
+		},
+		{
+			name: "rest parameter without rest arguments",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"params": ["$first", "$...rest"],
+									"body": {
+										"command": {
+											"symbol": "len",
+											"args": "$rest"
+										}
+									}
+								}
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$f",
+							"args": [1]
+						}
+					}
+				]`,
+			expected: 0,
+		},
+		{
+			name: "optional parameter with default value",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"params": [
+										"$x",
+										{"var": "$y", "default": 10},
+										{"var": "$z", "default": {"command": {"symbol": "*", "args": ["$y", 2]}}}
+									],
+									"body": ["$x", "$y", "$z"]
+								}
+							}
+						}
+					},
+					[
+						{
+							"command": {
+								"symbol": "$f",
+								"args": [1]
+							}
+						},
+						{
+							"command": {
+								"symbol": "$f",
+								"args": [1, 2]
+							}
+						},
+						{
+							"command": {
+								"symbol": "$f",
+								"args": [1, 2, 3]
+							}
+						}
+					]
+				]`,
+			expected: []any{[]any{1, 10, 20}, []any{1, 2, 4}, []any{1, 2, 3}},
+		},
+		{
+			name: "single array argument is not spread",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$arr",
+							"val": [1, 2, 3]
+						}
+					},
+					{
+						"set": {
+							"var": "$count",
+							"val": {
+								"lambda": {
+									"params": "$xs",
+									"body": {
+										"command": {
+											"symbol": "len",
+											"args": "$xs"
+										}
+									}
+								}
+							}
+						}
+					},
+					[
+						{
+							"command": {
+								"symbol": "$count",
+								"args": "$arr"
+							}
+						},
+						{
+							"command": {
+								"symbol": "$count",
+								"args": ["$arr"]
+							}
+						}
+					]
+				]`,
+			expected: []any{3, 3},
+		},
+		{
+			name: "spread array into arguments",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$arr",
+							"val": [2, 3]
+						}
+					},
+					{
+						"set": {
+							"var": "$add",
+							"val": {
+								"lambda": {
+									"params": ["$x", "$y", "$z"],
+									"body": {
+										"command": {
+											"symbol": "+",
+											"args": ["$x", "$y", "$z"]
+										}
+									}
+								}
+							}
+						}
+					},
+					[
+						{
+							"command": {
+								"symbol": "$add",
+								"args": [1, "$...arr"]
+							}
+						},
+						{
+							"command": {
+								"symbol": "*",
+								"args": ["$...arr"]
+							}
+						}
+					]
+				]`,
+			expected: []any{6, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			evaluatedArray, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Fatalf("object is not Array. got=%T", evaluated)
+			}
+			finalResult := evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, finalResult, int64(expected))
+			case []any:
+				testArrayObject(t, finalResult, expected)
+			}
+		})
+	}
+}
+
+func TestLambdaParametersError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "too few arguments with optional parameter",
+			input: `
+				{
+					"command": {
+						"symbol": {
+							"lambda": {
+								"params": ["$x", {"var": "$y", "default": 1}],
+								"body": "$x"
+							}
+						}
+					}
+				}`,
+			expected: "failed to apply function: wrong number of arguments. want=1..2, got=0",
+		},
+		{
+			name: "too few arguments with rest parameter",
+			input: `
+				{
+					"command": {
+						"symbol": {
+							"lambda": {
+								"params": ["$x", "$...rest"],
+								"body": "$x"
+							}
+						}
+					}
+				}`,
+			expected: "failed to apply function: wrong number of arguments. want at least 1, got=0",
+		},
+		{
+			name: "rest parameter not at the end",
+			input: `
+				{
+					"lambda": {
+						"params": ["$...rest", "$x"],
+						"body": "$x"
+					}
+				}`,
+			expected: "rest parameter must be the last parameter: \"$...rest\"",
+		},
+		{
+			name: "required parameter after optional parameter",
+			input: `
+				{
+					"lambda": {
+						"params": [{"var": "$x", "default": 1}, "$y"],
+						"body": "$x"
+					}
+				}`,
+			expected: "required parameter must not follow optional parameter: \"$y\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
+
+func TestBuiltinArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name:     "array literal is the list of arguments",
+			input:    `{"command": {"symbol": "len", "args": ["$a"]}}`,
+			expected: int64(2),
+		},
+		{
+			name:     "identifier of array is a single argument",
+			input:    `{"command": {"symbol": "json_stringify", "args": "$a"}}`,
+			expected: "[1,2]",
+		},
+		{
+			name:     "spread array into arguments of builtin",
+			input:    `{"command": {"symbol": "+", "args": ["$...a"]}}`,
+			expected: int64(3),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			evaluated := testEval(t, tt.input)
-			testIntegerObject(t, evaluated, tt.expected)
+			input := fmt.Sprintf(`[{"set": {"var": "$a", "val": [1, 2]}}, %s]`, tt.input)
+			evaluated := testEval(t, input)
+			evaluatedArray, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+			}
+			result := evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
+			switch expected := tt.expected.(type) {
+			case int64:
+				testIntegerObject(t, result, expected)
+			case string:
+				testStringObject(t, result, expected)
+			}
 		})
 	}
 }
 
-func TestLambdaExpression(t *testing.T) {
+func TestBuiltinArgumentsError(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
-		expected int64
+		expected string
 	}{
 		{
-			name: "call lambda expression directly",
+			name:     "identifier of array is not spread",
+			input:    `{"command": {"symbol": "+", "args": "$a"}}`,
+			expected: "argument to '+' must be NUMBER, got ARRAY",
+		},
+		{
+			name:     "too many arguments to builtin with single parameter",
+			input:    `{"command": {"symbol": "len", "args": ["$a", "$a"]}}`,
+			expected: "failed to apply function: wrong number of arguments. want=1, got=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := fmt.Sprintf(`[{"set": {"var": "$a", "val": [1, 2]}}, %s]`, tt.input)
+			evaluated := testEval(t, input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name: "bind arguments to parameters by name",
 			input: `
 				[
 					{
-						"command": {
-							"symbol": {
+						"set": {
+							"var": "$sub",
+							"val": {
 								"lambda": {
-									"params": "$x",
+									"params": ["$x", "$y"],
 									"body": {
 										"command": {
-											"symbol": "+",
-											"args": [1, "$x"]
+											"symbol": "-",
+											"args": ["$x", "$y"]
 										}
 									}
 								}
-							},
-							"args": 10
+							}
+						}
+					},
+					{
+						"command": {
+							"symbol": "$sub",
+							"args": {
+								"$y": 1,
+								"$x": 10
+							}
 						}
 					}
-				]
-			`,
-			expected: 11,
+				]`,
+			expected: 9,
 		},
 		{
-			name: "lambda expression without parameters",
+			name: "names are case sensitive",
 			input: `
 				[
 					{
@@ -700,156 +2227,227 @@ func TestLambdaExpression(t *testing.T) {
 							"var": "$f",
 							"val": {
 								"lambda": {
-									"body": 10
+									"params": ["$firstName", {"var": "$lastName", "default": "Doe"}],
+									"body": "{$firstName} {$lastName}"
 								}
 							}
 						}
 					},
 					{
 						"command": {
-							"symbol": "$f"
+							"symbol": "$f",
+							"args": {
+								"$firstName": "John"
+							}
 						}
 					}
 				]`,
-			expected: 10,
+			expected: "John Doe",
 		},
 		{
-			name: "lambda expression with a parameter",
+			name: "skip optional parameter",
 			input: `
 				[
 					{
 						"set": {
-							"var": "$addOne",
+							"var": "$f",
 							"val": {
 								"lambda": {
-									"params": "$x",
-									"body": {
-										"command": {
-											"symbol": "+",
-											"args": [1, "$x"]
-										}
-									}
+									"params": [
+										"$x",
+										{"var": "$y", "default": 2},
+										{"var": "$z", "default": 3},
+										"$...rest"
+									],
+									"body": ["$x", "$y", "$z", "$rest"]
 								}
 							}
 						}
 					},
 					{
 						"command": {
-							"symbol": "$addOne",
-							"args": 10
+							"symbol": "$f",
+							"args": {
+								"$x": 1,
+								"$z": 30,
+								"$rest": [4, 5]
+							}
 						}
 					}
 				]`,
-			expected: 11,
+			expected: []any{1, 2, 30, []any{4, 5}},
 		},
 		{
-			name: "lambda expression with multiple parameters",
+			name: "builtin function with named arguments",
 			input: `
-				[
-					{
-						"set": {
-							"var": "$addAndDouble",
-							"val": {
-								"lambda": {
-									"params": ["$x", "$y"],
-									"body": {
-										"command": {
-											"symbol": "*",
-											"args": [
-												{
-													"command": {
-														"symbol": "+",
-														"args": ["$x", "$y"]
-													}
-												},
-												2
-											]
-										}
-									}
-								}
+				{
+					"command": {
+						"symbol": "at",
+						"args": {
+							"$index": 1,
+							"$collection": [10, 20, 30]
+						}
+					}
+				}`,
+			expected: 20,
+		},
+		{
+			name: "builtin function with a single named argument",
+			input: `
+				{
+					"command": {
+						"symbol": "len",
+						"args": {
+							"$value": [10, 20, 30]
+						}
+					}
+				}`,
+			expected: 3,
+		},
+		{
+			name: "map literal is passed as a single argument",
+			input: `
+				{
+					"command": {
+						"symbol": "len",
+						"args": {
+							"map": {
+								"$x": 1,
+								"$y": 2
 							}
 						}
-					},
-					{
-						"command": {
-							"symbol": "$addAndDouble",
-							"args": [1, 2]
+					}
+				}`,
+			expected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			if evaluatedArray, ok := evaluated.(*object.Array); ok {
+				evaluated = evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
+			}
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				testStringObject(t, evaluated, expected)
+			case []any:
+				testArrayObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestNamedArgumentsError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "unknown named argument",
+			input: `
+				{
+					"command": {
+						"symbol": {
+							"lambda": {
+								"params": "$x",
+								"body": "$x"
+							}
+						},
+						"args": {
+							"$x": 1,
+							"$y": 2
 						}
 					}
-				]`,
-			expected: 6,
+				}`,
+			expected: "failed to apply function: unknown named argument: $y",
 		},
 		{
-			name: "lambda expression with return statement",
+			name: "missing named argument",
 			input: `
-				[
-					{
-						"set": {
-							"var": "$f",
-							"val": {
-								"lambda": {
-									"body": [
-										{
-											"set": {
-												"var": "$sum",
-												"val": 0
-											}
-										},
-										{
-											"loop": {
-												"for": "$i",
-												"from": 1,
-												"until": 11,
-												"do": {
-													"if": {
-														"cond": {
-															"command": {
-																"symbol": ">",
-																"args": ["$i", 5]
-															}
-														},
-														"conseq": {
-															"return": "$sum"
-														},
-														"alt": {
-															"set": {
-																"var": "$sum",
-																"val": {
-																	"command": {
-																		"symbol": "+",
-																		"args": ["$sum", "$i"]
-																	}
-																}
-															}
-														}
-													}
-												}
-											}
-										}
-									]
-								}
+				{
+					"command": {
+						"symbol": {
+							"lambda": {
+								"params": ["$x", "$y"],
+								"body": "$x"
 							}
+						},
+						"args": {
+							"$x": 1
 						}
-					},
-					{
-						"command": {
-							"symbol": "$f"
+					}
+				}`,
+			expected: "failed to apply function: missing argument for parameter \"$y\"",
+		},
+		{
+			name: "missing named argument of builtin function",
+			input: `
+				{
+					"command": {
+						"symbol": "at",
+						"args": {
+							"$index": 1
 						}
 					}
-				]`,
-			expected: 15,
+				}`,
+			expected: "failed to apply function: missing argument for parameter $collection",
+		},
+		{
+			name: "missing last named argument of builtin function",
+			input: `
+				{
+					"command": {
+						"symbol": "at",
+						"args": {
+							"$collection": [1, 2]
+						}
+					}
+				}`,
+			expected: "failed to apply function: missing argument for parameter $index",
+		},
+		{
+			name: "optional named argument after omitted one",
+			input: `
+				{
+					"command": {
+						"symbol": "json_stringify",
+						"args": {
+							"$value": 1,
+							"$sort_keys": true
+						}
+					}
+				}`,
+			expected: "failed to apply function: missing argument for parameter $indent",
+		},
+		{
+			name: "builtin function without parameter names",
+			input: `
+				{
+					"command": {
+						"symbol": "+",
+						"args": {
+							"$x": 1
+						}
+					}
+				}`,
+			expected: "failed to apply function: builtin function does not accept named arguments",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			evaluated := testEval(t, tt.input)
-			evaluatedArray, ok := evaluated.(*object.Array)
+			errObj, ok := evaluated.(*object.Error)
 			if !ok {
-				t.Fatalf("object is not Array. got=%T", evaluated)
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
 			}
-			finalResult := evaluatedArray.Elements[len(evaluatedArray.Elements)-1]
-			testIntegerObject(t, finalResult, tt.expected)
 		})
 	}
 }
@@ -870,6 +2468,8 @@ func testArrayObject(t *testing.T, obj object.Object, expected []any) {
 			testIntegerObject(t, result.Elements[i], int64(e))
 		case bool:
 			testBooleanObject(t, result.Elements[i], e)
+		case string:
+			testStringObject(t, result.Elements[i], e)
 		case []any:
 			testArrayObject(t, result.Elements[i], e)
 		}
@@ -0,0 +1,274 @@
+package evaluator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/JunNishimura/jsop/ast"
+	"github.com/JunNishimura/jsop/object"
+	"github.com/JunNishimura/jsop/token"
+)
+
+// symbolGenerator generates unique symbols during an expansion.
+// it is created for each expansion, so that the same program is always expanded into the same symbols.
+type symbolGenerator struct {
+	counter int
+	// written are the symbols written in the program, which are never generated.
+	written map[string]bool
+	// env is the environment of the program, whose variables are never generated as well.
+	env *object.Environment
+}
+
+// newSymbolGenerator records the symbols written in the program, including rest patterns and embedded identifiers.
+func newSymbolGenerator(program ast.Expression, env *object.Environment) *symbolGenerator {
+	written := make(map[string]bool)
+	re := regexp.MustCompile(identEmbedPattern)
+	ast.Inspect(program, func(exp ast.Expression) bool {
+		str, ok := exp.(*ast.StringLiteral)
+		if !ok {
+			return true
+		}
+
+		// keys are lowercased by the parser, and the original is kept in the token
+		for _, value := range []string{str.Value, str.Token.Literal} {
+			switch {
+			case strings.HasPrefix(value, restPrefix):
+				written[restVariable(value)] = true
+			case strings.HasPrefix(value, "$"):
+				written[value] = true
+			default:
+				for _, embedded := range re.FindAllString(value, -1) {
+					written[strings.TrimSpace(embedded[1:len(embedded)-1])] = true
+				}
+			}
+		}
+		return true
+	})
+
+	return &symbolGenerator{written: written, env: env}
+}
+
+// gensym generates a unique symbol from the prefix. e.g. "$tmp" -> "$tmp__1"
+// the symbol written in the program or bound in the environment is skipped,
+// so that it does not collide with the user's variable.
+func (g *symbolGenerator) gensym(prefix string) string {
+	for {
+		g.counter++
+		symbol := fmt.Sprintf("%s__%d", prefix, g.counter)
+		if _, ok := g.env.Get(symbol); !ok && !g.written[symbol] {
+			return symbol
+		}
+	}
+}
+
+// builtin returns the gensym builtin function which generates symbols by the generator.
+func (g *symbolGenerator) builtin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args object.Object) object.Object {
+			if args == Null {
+				return &object.String{Value: g.gensym("$G")}
+			}
+
+			prefix, ok := args.(*object.String)
+			if !ok {
+				return newError("argument to 'gensym' must be STRING, got %s", args.Type())
+			}
+			return &object.String{Value: g.gensym("$" + strings.TrimPrefix(prefix.Value, "$"))}
+		},
+		Params:         []string{"$prefix"},
+		OptionalParams: 1,
+	}
+}
+
+// renameMacroBindings renames the variables bound in the expansion of a hygienic macro,
+// so that they neither capture nor clobber the variables of the caller.
+// userExps are the expressions passed to the macro, which are left as they are.
+func renameMacroBindings(exp ast.Expression, userExps map[ast.Expression]bool, symbols *symbolGenerator) ast.Expression {
+	renames := make(map[string]string)
+	collectMacroBindings(exp, userExps, renames, symbols)
+	if len(renames) == 0 {
+		return exp
+	}
+
+	return renameSymbols(exp, userExps, renames)
+}
+
+// addUserExpressions records the expression passed to the macro as the user's.
+// the elements of an array and the values of an object are recorded as well,
+// since they are placed apart from their container by unquote-splice.
+func addUserExpressions(userExps map[ast.Expression]bool, exp ast.Expression) {
+	userExps[exp] = true
+
+	switch exp := exp.(type) {
+	case *ast.Array:
+		for _, el := range exp.Elements {
+			userExps[el] = true
+		}
+	case *ast.KeyValueObject:
+		for _, kv := range exp.KV {
+			userExps[kv.Value] = true
+		}
+	}
+}
+
+func collectMacroBindings(exp ast.Expression, userExps map[ast.Expression]bool, renames map[string]string, symbols *symbolGenerator) {
+	ast.Inspect(exp, func(node ast.Expression) bool {
+		if userExps[node] {
+			return false
+		}
+
+		kvObj, ok := node.(*ast.KeyValueObject)
+		if !ok {
+			return true
+		}
+		for _, pattern := range bindingPatterns(kvObj, userExps) {
+			// the name given by the caller is not renamed
+			if userExps[pattern] {
+				continue
+			}
+			variables, err := patternVariables(pattern)
+			if err != nil {
+				continue
+			}
+			for _, variable := range variables {
+				if _, ok := renames[variable]; !ok {
+					renames[variable] = symbols.gensym(variable)
+				}
+			}
+		}
+		return true
+	})
+}
+
+// bindingPatterns returns the patterns which bind variables in the special form.
+// the special forms given by the user, e.g. spliced pairs, are skipped.
+func bindingPatterns(kvObj *ast.KeyValueObject, userExps map[ast.Expression]bool) []ast.Expression {
+	patterns := make([]ast.Expression, 0)
+
+	for key, value := range kvObj.KVPairs() {
+		if userExps[value] {
+			continue
+		}
+		valueObj, ok := value.(*ast.KeyValueObject)
+		if !ok {
+			continue
+		}
+		kvPairs := valueObj.KVPairs()
+
+		switch key {
+		case "set":
+			if pattern, ok := kvPairs["var"]; ok {
+				patterns = append(patterns, pattern)
+			}
+		case "loop":
+			if pattern, ok := kvPairs["for"]; ok {
+				patterns = append(patterns, pattern)
+			}
+		case "lambda":
+			params, ok := kvPairs["params"]
+			if !ok {
+				continue
+			}
+			paramsArray, ok := params.(*ast.Array)
+			if !ok {
+				paramsArray = &ast.Array{Elements: []ast.Expression{params}}
+			}
+			for _, param := range paramsArray.Elements {
+				pattern, _, _ := splitParameter(param)
+				patterns = append(patterns, pattern)
+			}
+		case "match":
+			clauses, ok := kvPairs["clauses"].(*ast.Array)
+			if !ok {
+				continue
+			}
+			for _, clause := range clauses.Elements {
+				clauseObj, ok := clause.(*ast.KeyValueObject)
+				if !ok {
+					continue
+				}
+				if pattern, ok := clauseObj.KVPairs()["pattern"]; ok {
+					patterns = append(patterns, pattern)
+				}
+			}
+		}
+	}
+
+	return patterns
+}
+
+// renameSymbols returns the copy of the expression whose symbols are renamed.
+// the expression itself is not modified because it may be a part of the macro body.
+func renameSymbols(exp ast.Expression, userExps map[ast.Expression]bool, renames map[string]string) ast.Expression {
+	if userExps[exp] {
+		return exp
+	}
+
+	switch exp := exp.(type) {
+	case *ast.StringLiteral:
+		renamed := renameSymbol(exp.Value, renames)
+		if renamed == exp.Value {
+			return exp
+		}
+		return &ast.StringLiteral{
+			Token: token.Token{Type: token.STRING, Literal: renamed, Pos: exp.Token.Pos},
+			Value: renamed,
+		}
+	case *ast.Array:
+		elements := make([]ast.Expression, len(exp.Elements))
+		for i, el := range exp.Elements {
+			elements[i] = renameSymbols(el, userExps, renames)
+		}
+		return &ast.Array{Token: exp.Token, Elements: elements, Comments: exp.Comments}
+	case *ast.KeyValueObject:
+		// the keys of named arguments are the parameters of the function, which may be renamed
+		isNamed := isNamedArguments(exp)
+		kvs := make([]*ast.KeyValuePair, len(exp.KV))
+		for i, kv := range exp.KV {
+			key := kv.Key
+			if isNamed {
+				key = renameKey(key, renames)
+			}
+			kvs[i] = &ast.KeyValuePair{Key: key, Value: renameSymbols(kv.Value, userExps, renames)}
+		}
+		return &ast.KeyValueObject{Token: exp.Token, KV: kvs, Comments: exp.Comments}
+	default:
+		return exp
+	}
+}
+
+// renameKey renames the key of named arguments.
+// the original case is kept in the token, and the value is lowercased like the keys parsed from the source.
+func renameKey(key *ast.StringLiteral, renames map[string]string) *ast.StringLiteral {
+	renamed, ok := renames[key.Token.Literal]
+	if !ok {
+		return key
+	}
+	return &ast.StringLiteral{
+		Token: token.Token{Type: token.STRING, Literal: renamed, Pos: key.Token.Pos},
+		Value: strings.ToLower(renamed),
+	}
+}
+
+// renameSymbol renames the symbol, the rest pattern and the identifiers embedded in the string.
+func renameSymbol(value string, renames map[string]string) string {
+	if renamed, ok := renames[value]; ok {
+		return renamed
+	}
+	if strings.HasPrefix(value, restPrefix) {
+		if renamed, ok := renames[restVariable(value)]; ok {
+			return restPrefix + strings.TrimPrefix(renamed, "$")
+		}
+		return value
+	}
+
+	re := regexp.MustCompile(identEmbedPattern)
+	return re.ReplaceAllStringFunc(value, func(embedded string) string {
+		name := strings.TrimSpace(embedded[1 : len(embedded)-1])
+		if renamed, ok := renames[name]; ok {
+			return "{" + renamed + "}"
+		}
+		return embedded
+	})
+}
@@ -0,0 +1,161 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+var jsonBuiltins = map[string]*object.Builtin{
+	"json_parse": {
+		Fn: func(args object.Object) object.Object {
+			str, ok := args.(*object.String)
+			if !ok {
+				return newError("argument to 'json_parse' must be STRING, got %s", args.Type())
+			}
+
+			decoder := json.NewDecoder(strings.NewReader(str.Value))
+			decoder.UseNumber()
+
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return newError("invalid JSON: %s", err)
+			}
+			if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+				return newError("invalid JSON: unexpected data after the value")
+			}
+
+			return value
+		},
+		Params: []string{"$text"},
+	},
+	"json_stringify": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, ok := args.(*object.Array)
+			if !ok {
+				return newError("argument to 'json_stringify' must be ARRAY, got %s", args.Type())
+			}
+			if len(arrayArg.Elements) == 0 || len(arrayArg.Elements) > 3 {
+				return newError("number of arguments to 'json_stringify' must be 1 to 3, got %d", len(arrayArg.Elements))
+			}
+
+			indent := ""
+			if len(arrayArg.Elements) >= 2 {
+				switch indentArg := arrayArg.Elements[1].(type) {
+				case *object.Integer:
+					if indentArg.Value < 0 {
+						return newError("indent of 'json_stringify' must not be negative, got %d", indentArg.Value)
+					}
+					indent = strings.Repeat(" ", int(indentArg.Value))
+				case *object.String:
+					indent = indentArg.Value
+				default:
+					return newError("second argument to 'json_stringify' must be INTEGER or STRING, got %s", indentArg.Type())
+				}
+			}
+
+			sortKeys := false
+			if len(arrayArg.Elements) == 3 {
+				sortKeysArg, ok := arrayArg.Elements[2].(*object.Boolean)
+				if !ok {
+					return newError("third argument to 'json_stringify' must be BOOLEAN, got %s", arrayArg.Elements[2].Type())
+				}
+				sortKeys = sortKeysArg.Value
+			}
+
+			str, err := object.ToJSON(arrayArg.Elements[0], indent, sortKeys)
+			if err != nil {
+				return newError("%s", err)
+			}
+			return &object.String{Value: str}
+		},
+		Params:         []string{"$value", "$indent", "$sort_keys"},
+		OptionalParams: 2,
+	},
+}
+
+func init() {
+	for name, builtin := range jsonBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+// decodeJSON reads a JSON value token by token so that the order of keys is kept in the map.
+func decodeJSON(decoder *json.Decoder) (object.Object, error) {
+	token, err := decoder.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected end of JSON input")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case nil:
+		return Null, nil
+	case bool:
+		return nativeBoolToBooleanObject(token), nil
+	case string:
+		return &object.String{Value: token}, nil
+	case json.Number:
+		return decodeJSONNumber(token)
+	case json.Delim:
+		if token == '[' {
+			elements := make([]object.Object, 0)
+			for decoder.More() {
+				el, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, el)
+			}
+			// consume ']'
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elements}, nil
+		}
+
+		mapObj := object.NewMap()
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			mapObj.Set(keyToken.(string), value)
+		}
+		// consume '}'
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return mapObj, nil
+	default:
+		return nil, fmt.Errorf("unexpected token: %v", token)
+	}
+}
+
+func decodeJSONNumber(number json.Number) (object.Object, error) {
+	if strings.ContainsAny(number.String(), ".eE") {
+		value, err := strconv.ParseFloat(number.String(), 64)
+		if err != nil {
+			return nil, err
+		}
+		return &object.Float{Value: value}, nil
+	}
+
+	value, ok := new(big.Int).SetString(number.String(), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", number)
+	}
+	return normalizeBigInt(value), nil
+}
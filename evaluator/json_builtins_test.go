@@ -0,0 +1,185 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+func TestJSONParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name:     "parse integer",
+			input:    `{"command": {"symbol": "json_parse", "args": "42"}}`,
+			expected: 42,
+		},
+		{
+			name:     "parse string",
+			input:    `{"command": {"symbol": "json_parse", "args": "\"hello\""}}`,
+			expected: "hello",
+		},
+		{
+			name:     "parse array",
+			input:    `{"command": {"symbol": "json_parse", "args": "[1, true, \"a\", [2]]"}}`,
+			expected: []any{1, true, "a", []any{2}},
+		},
+		{
+			name: "access parsed map",
+			input: `
+				{
+					"command": {
+						"symbol": "at",
+						"args": [
+							{
+								"command": {
+									"symbol": "json_parse",
+									"args": "{\"Name\": \"jsop\", \"tags\": [\"json\"]}"
+								}
+							},
+							"Name"
+						]
+					}
+				}`,
+			expected: "jsop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				testStringObject(t, evaluated, expected)
+			case []any:
+				testArrayObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestJSONParseNumbers(t *testing.T) {
+	input := `{"command": {"symbol": "json_parse", "args": "[1.5, 123456789012345678901234567890, null]"}}`
+
+	evaluated := testEval(t, input)
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	testFloatObject(t, array.Elements[0], 1.5)
+	testBigIntObject(t, array.Elements[1], "123456789012345678901234567890")
+	if array.Elements[2] != Null {
+		t.Errorf("object is not Null. got=%T (%+v)", array.Elements[2], array.Elements[2])
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "stringify array of strings",
+			input:    `{"command": {"symbol": "json_stringify", "args": [["a", "b<c>", "\"q\""]]}}`,
+			expected: `["a","b<c>","\"q\""]`,
+		},
+		{
+			name:     "stringify map keeps the order of keys",
+			input:    `{"command": {"symbol": "json_stringify", "args": [{"map": {"b": 1, "A": [1.5, false]}}]}}`,
+			expected: `{"b":1,"A":[1.5,false]}`,
+		},
+		{
+			name:     "stringify map with sorted keys",
+			input:    `{"command": {"symbol": "json_stringify", "args": [{"map": {"b": 1, "a": 2}}, 0, true]}}`,
+			expected: `{"a":2,"b":1}`,
+		},
+		{
+			name:  "stringify with indent",
+			input: `{"command": {"symbol": "json_stringify", "args": {"$value": {"map": {"a": [1, 2]}}, "$indent": 2}}}`,
+			expected: `{
+  "a": [
+    1,
+    2
+  ]
+}`,
+		},
+		{
+			name:     "stringify quote as string",
+			input:    `{"command": {"symbol": "json_stringify", "args": [{"command": {"symbol": "quote", "args": 1}}]}}`,
+			expected: `"1"`,
+		},
+		{
+			name: "round trip",
+			input: `
+				{
+					"command": {
+						"symbol": "json_stringify",
+						"args": [
+							{
+								"command": {
+									"symbol": "json_parse",
+									"args": "{\"z\": [1, 2.5, \"x\"], \"a\": {\"nested\": false}}"
+								}
+							}
+						]
+					}
+				}`,
+			expected: `{"z":[1,2.5,"x"],"a":{"nested":false}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testStringObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestJSONBuiltinsError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "parse invalid JSON",
+			input:    `{"command": {"symbol": "json_parse", "args": "[1, 2"}}`,
+			expected: "invalid JSON: unexpected end of JSON input",
+		},
+		{
+			name:     "parse trailing data",
+			input:    `{"command": {"symbol": "json_parse", "args": "1 2"}}`,
+			expected: "invalid JSON: unexpected data after the value",
+		},
+		{
+			name:     "parse non string",
+			input:    `{"command": {"symbol": "json_parse", "args": 1}}`,
+			expected: "argument to 'json_parse' must be STRING, got INTEGER",
+		},
+		{
+			name:     "stringify with invalid indent",
+			input:    `{"command": {"symbol": "json_stringify", "args": [1, true]}}`,
+			expected: "second argument to 'json_stringify' must be INTEGER or STRING, got BOOLEAN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
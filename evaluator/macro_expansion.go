@@ -2,36 +2,57 @@ package evaluator
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/JunNishimura/jsop/ast"
 	"github.com/JunNishimura/jsop/object"
+	"github.com/JunNishimura/jsop/token"
 )
 
-func DefineMacros(program ast.Expression, env *object.Environment) error {
-	if arrayExp, ok := program.(*ast.Array); ok {
-		definitions := make([]int, 0)
+// macroExpansionLimit is the maximum depth of nested expansions,
+// which stops a macro expanding into itself forever.
+const macroExpansionLimit = 1000
 
-		for i, exp := range arrayExp.Elements {
-			if macro, ok := isMacroDefinition(exp); ok {
-				if err := addMacro(macro, env); err != nil {
-					return err
-				}
-				definitions = append(definitions, i)
-			}
-		}
+// macroRestPrefix is the prefix of the key which collects the keys not declared in the macro.
+const macroRestPrefix = "..."
 
-		for i := len(definitions) - 1; i >= 0; i = i - 1 {
-			definitionIndex := definitions[i]
-			arrayExp.Elements = append(
-				arrayExp.Elements[:definitionIndex],
-				arrayExp.Elements[definitionIndex+1:]...,
-			)
-		}
+// ExpansionError is an error of macro expansion with the name of the macro and the position of its call.
+type ExpansionError struct {
+	Macro   string
+	Message string
+	Pos     token.Position
+}
+
+func (ee *ExpansionError) Error() string {
+	// the call made by a macro may have no position
+	if ee.Pos.Line == 0 {
+		return fmt.Sprintf("macro %s: %s", ee.Macro, ee.Message)
+	}
+	return fmt.Sprintf("macro %s: %s at line %d, column %d", ee.Macro, ee.Message, ee.Pos.Line, ee.Pos.Column)
+}
+
+func newExpansionError(macroName string, call *ast.KeyValueObject, format string, a ...interface{}) *ExpansionError {
+	return &ExpansionError{
+		Macro:   macroName,
+		Message: fmt.Sprintf(format, a...),
+		Pos:     call.Token.Pos,
+	}
+}
+
+// DefineMacros defines the macros written at the top level of the program.
+// the program is not changed, and the definitions are skipped when the macros are expanded.
+func DefineMacros(program ast.Expression, env *object.Environment) error {
+	topLevels := []ast.Expression{program}
+	if arrayExp, ok := program.(*ast.Array); ok {
+		topLevels = arrayExp.Elements
 	}
 
-	if macro, ok := isMacroDefinition(program); ok {
-		if err := addMacro(macro, env); err != nil {
-			return err
+	for _, exp := range topLevels {
+		if macro, ok := isMacroDefinition(exp); ok {
+			if err := addMacro(macro, env); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -69,21 +90,45 @@ func addMacro(macro *ast.KeyValueObject, env *object.Environment) error {
 	}
 
 	keys := make([]*ast.StringLiteral, 0)
+	defaults := make(map[string]ast.Expression)
+	restKey := ""
 	keysValue, ok := kvPairs["keys"]
 	if ok {
-		switch keysValue := keysValue.(type) {
-		case *ast.Array:
-			for _, key := range keysValue.Elements {
-				strLiteral, ok := key.(*ast.StringLiteral)
+		keyElements := []ast.Expression{keysValue}
+		if keysArray, ok := keysValue.(*ast.Array); ok {
+			keyElements = keysArray.Elements
+		}
+
+		for i, keyElement := range keyElements {
+			switch key := keyElement.(type) {
+			case *ast.StringLiteral:
+				if strings.HasPrefix(key.Value, macroRestPrefix) {
+					if i != len(keyElements)-1 {
+						return fmt.Errorf("rest key must be the last key of macro: %s", key.Value)
+					}
+					restKey = strings.TrimPrefix(key.Value, macroRestPrefix)
+					continue
+				}
+				if len(defaults) > 0 {
+					return fmt.Errorf("required key must not follow optional key: %s", key.Value)
+				}
+				keys = append(keys, key)
+			case *ast.KeyValueObject:
+				// optional key is written as {"key": name, "default": value}
+				keyPairs := key.KVPairs()
+				name, ok := keyPairs["key"].(*ast.StringLiteral)
 				if !ok {
-					return fmt.Errorf("macro expects 'keys' to be Array of StringLiterals")
+					return fmt.Errorf("macro expects optional key to have 'key' key of StringLiteral, got %s", key)
 				}
-				keys = append(keys, strLiteral)
+				defaultValue, ok := keyPairs["default"]
+				if !ok {
+					return fmt.Errorf("macro expects optional key to have 'default' key, got %s", key)
+				}
+				keys = append(keys, name)
+				defaults[name.Value] = defaultValue
+			default:
+				return fmt.Errorf("macro expects 'keys' to be Array of StringLiterals or optional keys")
 			}
-		case *ast.StringLiteral:
-			keys = append(keys, keysValue)
-		default:
-			return fmt.Errorf("macro expects 'keys' to be Array or StringLiteral")
 		}
 	}
 
@@ -92,74 +137,255 @@ func addMacro(macro *ast.KeyValueObject, env *object.Environment) error {
 		return fmt.Errorf("macro expects 'body' key")
 	}
 
+	hygienic := false
+	if hygienicVal, ok := kvPairs["hygienic"]; ok {
+		hygienicBool, ok := hygienicVal.(*ast.Boolean)
+		if !ok {
+			return fmt.Errorf("macro expects 'hygienic' key to be Boolean, got %s", hygienicVal)
+		}
+		hygienic = hygienicBool.Value
+	}
+
 	macroObj := &object.Macro{
-		Keys: keys,
-		Body: bodyVal,
-		Env:  env,
+		Keys:     keys,
+		Defaults: defaults,
+		RestKey:  restKey,
+		Body:     bodyVal,
+		Env:      env,
+		Hygienic: hygienic,
 	}
-	env.Set(macroName.Value, macroObj)
+	// a macro in a nested array shadows the outer one with the same name
+	env.Define(macroName.Value, macroObj)
 
 	return nil
 }
 
-func ExpandMacros(program ast.Expression, env *object.Environment) ast.Expression {
-	return ast.Modify(program, func(exp ast.Expression) ast.Expression {
-		kvObj, ok := exp.(*ast.KeyValueObject)
-		if !ok {
-			return exp
+// ExpandMacros replaces the macro calls in the program with their expansions.
+// it reports an error if a macro call is invalid, e.g. a required key is missing.
+// the macros defined in a nested array are visible only in that array.
+func ExpandMacros(program ast.Expression, env *object.Environment) (ast.Expression, error) {
+	symbols := newSymbolGenerator(program, env)
+	// gensym is defined for each expansion, so that the macros and the program share the generated symbols
+	env.Define("gensym", symbols.builtin())
+
+	if array, ok := program.(*ast.Array); ok {
+		return expandBlock(array, env, symbols, 0)
+	}
+
+	// the program which is not an array is expanded as a block of one element
+	block := &ast.Array{
+		Token:    token.Token{Type: token.LBRACKET, Literal: "["},
+		Elements: []ast.Expression{program},
+	}
+	expanded, err := expandBlock(block, env, symbols, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(expanded.Elements) == 1 {
+		return expanded.Elements[0], nil
+	}
+	return expanded, nil
+}
+
+// expandBlock defines the macros in the array and expands the macro calls in its elements.
+// the definitions written in the array are visible in the whole array,
+// while the definitions resulting from expansions are visible in the following elements.
+func expandBlock(array *ast.Array, scope *object.Environment, symbols *symbolGenerator, depth int) (*ast.Array, error) {
+	for _, el := range array.Elements {
+		if macro, ok := isMacroDefinition(el); ok {
+			if err := addMacro(macro, scope); err != nil {
+				return nil, err
+			}
 		}
+	}
 
-		macroName, macroObj, ok := isMacroCall(kvObj, env)
-		if !ok {
-			return exp
+	expandedElements := make([]ast.Expression, 0, len(array.Elements))
+	// places are the indexes in the expanded elements, which keep the places of the comments
+	places := make([]int, len(array.Elements)+1)
+	for i, el := range array.Elements {
+		places[i] = len(expandedElements)
+		if _, ok := isMacroDefinition(el); ok {
+			continue
 		}
 
-		body, ok := kvObj.KVPairs()[macroName]
-		if !ok {
-			return exp
+		expanded, err := expandMacros(el, scope, symbols, depth)
+		if err != nil {
+			return nil, err
 		}
-		bodyObj, ok := body.(*ast.KeyValueObject)
-		if !ok {
-			return exp
+		// a macro may expand into another macro definition
+		if macro, ok := isMacroDefinition(expanded); ok {
+			if err := addMacro(macro, scope); err != nil {
+				return nil, err
+			}
+			continue
 		}
+		expandedElements = append(expandedElements, expanded)
+	}
+	places[len(array.Elements)] = len(expandedElements)
 
-		quotedKeys, ok := quoteKeys(macroObj, bodyObj)
-		if !ok {
-			return exp
+	return &ast.Array{Token: array.Token, Elements: expandedElements, Comments: placeComments(array.Comments, places)}, nil
+}
+
+func expandMacros(exp ast.Expression, env *object.Environment, symbols *symbolGenerator, depth int) (ast.Expression, error) {
+	switch exp := exp.(type) {
+	case *ast.Array:
+		return expandBlock(exp, object.NewEnclosedEnvironment(env), symbols, depth)
+	case *ast.KeyValueObject:
+		// the body of a macro definition is expanded when the macro is called
+		if _, ok := isMacroDefinition(exp); ok {
+			return exp, nil
+		}
+
+		// the quoted expression is left as it is written
+		if isCommand(exp, "quote") {
+			return exp, nil
 		}
 
-		macroEnv := extendMacroEnv(macroObj, quotedKeys)
+		if macroName, macroObj, ok := isMacroCall(exp, env); ok {
+			if depth >= macroExpansionLimit {
+				return nil, newExpansionError(macroName, exp, "exceeds the expansion depth limit of %d", macroExpansionLimit)
+			}
+			expansion, err := expandMacro(macroName, macroObj, exp, symbols)
+			if err != nil {
+				return nil, err
+			}
+			// the expansion is expanded again until no macro call is left
+			return expandMacros(expansion, env, symbols, depth+1)
+		}
 
-		evaluated := Eval(macroObj.Body, macroEnv)
+		kvs := make([]*ast.KeyValuePair, len(exp.KV))
+		for i, kv := range exp.KV {
+			value, err := expandMacros(kv.Value, env, symbols, depth)
+			if err != nil {
+				return nil, err
+			}
+			kvs[i] = &ast.KeyValuePair{Key: kv.Key, Value: value}
+		}
+		return &ast.KeyValueObject{Token: exp.Token, KV: kvs, Comments: exp.Comments}, nil
+	default:
+		return exp, nil
+	}
+}
 
-		quote, ok := evaluated.(*object.Quote)
+// macroexpand returns the expansion of the quoted expression as a quote.
+// macroexpand-1 expands only the outermost macro call once,
+// while macroexpand expands the expression until no macro call is left.
+func macroexpand(symbol string, argsValue ast.Expression, env *object.Environment) object.Object {
+	args := Eval(argsValue, env)
+	if isError(args) {
+		return args
+	}
+	quoted, ok := args.(*object.Quote)
+	if !ok {
+		return newError("argument to '%s' must be QUOTE, got %s", symbol, args.Type())
+	}
+
+	// the symbols are generated apart from the expansion of the program, avoiding the variables bound so far
+	symbols := newSymbolGenerator(quoted.Expression, env)
+
+	if symbol == "macroexpand-1" {
+		kvObj, ok := quoted.Expression.(*ast.KeyValueObject)
 		if !ok {
-			return exp
+			return quoted
+		}
+		macroName, macroObj, ok := isMacroCall(kvObj, env)
+		if !ok {
+			return quoted
+		}
+		expansion, err := expandMacro(macroName, macroObj, kvObj, symbols)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return &object.Quote{Expression: expansion}
+	}
+
+	expanded, err := expandMacros(quoted.Expression, env, symbols, 0)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return &object.Quote{Expression: expanded}
+}
+
+func expandMacro(macroName string, macroObj *object.Macro, kvObj *ast.KeyValueObject, symbols *symbolGenerator) (ast.Expression, error) {
+	body := kvObj.KVPairs()[macroName]
+	bodyObj, ok := body.(*ast.KeyValueObject)
+	if !ok {
+		return nil, newExpansionError(macroName, kvObj, "expects OBJECT, got %s", body)
+	}
+
+	quotedKeys, err := quoteKeys(macroObj, bodyObj)
+	if err != nil {
+		return nil, newExpansionError(macroName, kvObj, "%s", err)
+	}
+
+	macroEnv := extendMacroEnv(macroObj, quotedKeys)
+
+	evaluated := unwrapReturnValue(Eval(macroObj.Body, macroEnv))
+	// the body written as an array results in its last value
+	if array, ok := evaluated.(*object.Array); ok && isArrayExpression(macroObj.Body) && len(array.Elements) > 0 {
+		evaluated = array.Elements[len(array.Elements)-1]
+	}
+
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, newExpansionError(macroName, kvObj, "%s", errObj.Message)
+	}
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		return nil, newExpansionError(macroName, kvObj, "must result in QUOTE, got %s", evaluated.Type())
+	}
+
+	if macroObj.Hygienic {
+		userExps := make(map[ast.Expression]bool)
+		for _, quotedKey := range quotedKeys {
+			addUserExpressions(userExps, quotedKey.Expression)
 		}
+		return renameMacroBindings(quote.Expression, userExps, symbols), nil
+	}
+
+	return quote.Expression, nil
+}
 
-		return quote.Expression
-	})
+func isArrayExpression(exp ast.Expression) bool {
+	_, ok := exp.(*ast.Array)
+	return ok
 }
 
-func quoteKeys(macroObj *object.Macro, body *ast.KeyValueObject) (map[string]*object.Quote, bool) {
+// quoteKeys quotes the values of the keys in the macro call.
+// optional keys which are not given are quoted from their default values,
+// and the keys which are not declared are collected into an object for the rest key.
+func quoteKeys(macroObj *object.Macro, body *ast.KeyValueObject) (map[string]*object.Quote, error) {
 	quotedKeys := make(map[string]*object.Quote)
 
 	kvPairs := body.KVPairs()
 	for _, key := range macroObj.Keys {
 		value, ok := kvPairs[key.Value]
 		if !ok {
-			return nil, false
+			defaultValue, isOptional := macroObj.Defaults[key.Value]
+			if !isOptional {
+				return nil, fmt.Errorf("missing key %s", key.Value)
+			}
+			value = defaultValue
 		}
 		quotedKeys[key.Value] = &object.Quote{Expression: value}
 	}
 
-	return quotedKeys, true
+	if macroObj.RestKey != "" {
+		rest := &ast.KeyValueObject{Token: body.Token, KV: []*ast.KeyValuePair{}}
+		for _, kv := range body.KV {
+			if !slices.ContainsFunc(macroObj.Keys, func(key *ast.StringLiteral) bool { return key.Value == kv.Key.Value }) {
+				rest.KV = append(rest.KV, kv)
+			}
+		}
+		quotedKeys[macroObj.RestKey] = &object.Quote{Expression: rest}
+	}
+
+	return quotedKeys, nil
 }
 
 func extendMacroEnv(macro *object.Macro, quotedKeys map[string]*object.Quote) *object.Environment {
 	extended := object.NewEnclosedEnvironment(macro.Env)
-	for _, key := range macro.Keys {
-		extended.Set(key.Value, quotedKeys[key.Value])
+	for name, quoted := range quotedKeys {
+		extended.Define(name, quoted)
 	}
 	return extended
 }
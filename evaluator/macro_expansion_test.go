@@ -1,6 +1,8 @@
 package evaluator
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/JunNishimura/jsop/ast"
@@ -193,10 +195,841 @@ func TestExpandMacro(t *testing.T) {
 		if err := DefineMacros(program, env); err != nil {
 			t.Fatalf("define macro error: %s", err)
 		}
-		expanded := ExpandMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("expand macro error: %s", err)
+		}
 
 		if expanded.String() != expected.String() {
 			t.Errorf("not equal. got=%q, want=%q", expanded.String(), expected.String())
 		}
 	}
 }
+
+func testEvalWithMacros(t *testing.T, input string) object.Object {
+	program, err := testParseProgram(input)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+
+	env := object.NewEnvironment()
+	if err := DefineMacros(program, env); err != nil {
+		t.Fatalf("define macro error: %s", err)
+	}
+	expanded, err := ExpandMacros(program, env)
+	if err != nil {
+		t.Fatalf("expand macro error: %s", err)
+	}
+
+	return Eval(expanded, env)
+}
+
+func TestHygienicMacro(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []any
+	}{
+		{
+			name: "hygienic macro does not clobber the caller's variable",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "twice",
+							"keys": ["expr"],
+							"hygienic": true,
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": [
+										{"set": {"var": "$tmp", "val": ",expr"}},
+										{"command": {"symbol": "+", "args": ["$tmp", "$tmp"]}}
+									]
+								}
+							}
+						}
+					},
+					{"set": {"var": "$tmp", "val": 10}},
+					{"twice": {"expr": {"command": {"symbol": "+", "args": ["$tmp", 1]}}}},
+					"$tmp"
+				]`,
+			expected: []any{10, []any{11, 22}, 10},
+		},
+		{
+			name: "non hygienic macro clobbers the caller's variable",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "twice",
+							"keys": ["expr"],
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": [
+										{"set": {"var": "$tmp", "val": ",expr"}},
+										{"command": {"symbol": "+", "args": ["$tmp", "$tmp"]}}
+									]
+								}
+							}
+						}
+					},
+					{"set": {"var": "$tmp", "val": 10}},
+					{"twice": {"expr": {"command": {"symbol": "+", "args": ["$tmp", 1]}}}},
+					"$tmp"
+				]`,
+			expected: []any{10, []any{11, 22}, 11},
+		},
+		{
+			name: "hygienic macro renames lambda parameters and embedded identifiers",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "describe",
+							"keys": ["value"],
+							"hygienic": true,
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": {
+										"command": {
+											"symbol": {
+												"lambda": {
+													"params": ["$x"],
+													"body": "{$x} and {$y}"
+												}
+											},
+											"args": [",value"]
+										}
+									}
+								}
+							}
+						}
+					},
+					{"set": {"var": "$x", "val": "outer x"}},
+					{"set": {"var": "$y", "val": "outer y"}},
+					{"describe": {"value": "$x"}}
+				]`,
+			expected: []any{"outer x", "outer y", "outer x and outer y"},
+		},
+		{
+			name: "gensym in macro body",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "square",
+							"keys": ["expr"],
+							"body": [
+								{"set": {"var": "$name", "val": {"command": {"symbol": "gensym", "args": "tmp"}}}},
+								{
+									"command": {
+										"symbol": "quote",
+										"args": [
+											{"set": {"var": ",$name", "val": ",expr"}},
+											{"command": {"symbol": "*", "args": [",$name", ",$name"]}}
+										]
+									}
+								}
+							]
+						}
+					},
+					{"set": {"var": "$tmp", "val": 3}},
+					{"square": {"expr": "$tmp"}},
+					"$tmp"
+				]`,
+			expected: []any{3, []any{3, 9}, 3},
+		},
+		{
+			name: "spliced statements of the user are not renamed",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "with_tmp",
+							"keys": ["stmts"],
+							"hygienic": true,
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": [
+										{"set": {"var": "$tmp", "val": 100}},
+										",@stmts"
+									]
+								}
+							}
+						}
+					},
+					{"set": {"var": "$tmp", "val": 1}},
+					{
+						"with_tmp": {
+							"stmts": [
+								{"set": {"var": "$tmp", "val": {"command": {"symbol": "+", "args": ["$tmp", 1]}}}},
+								{"set": {"var": "$tmp", "val": {"command": {"symbol": "+", "args": ["$tmp", 1]}}}}
+							]
+						}
+					},
+					"$tmp"
+				]`,
+			expected: []any{1, []any{100, 2, 3}, 3},
+		},
+		{
+			name: "spliced rest pairs of the user are not renamed",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "with_tmp",
+							"keys": ["...rest"],
+							"hygienic": true,
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": [
+										{"set": {"var": "$tmp", "val": 100}},
+										{"...": ",@rest"}
+									]
+								}
+							}
+						}
+					},
+					{"set": {"var": "$tmp", "val": 1}},
+					{"with_tmp": {"set": {"var": "$tmp", "val": 5}}},
+					"$tmp"
+				]`,
+			expected: []any{1, []any{100, 5}, 5},
+		},
+		{
+			name: "hygienic macro renames keys of named arguments",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "call_named",
+							"keys": ["v"],
+							"hygienic": true,
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": {
+										"command": {
+											"symbol": {"lambda": {"params": ["$x"], "body": "$x"}},
+											"args": {"$x": ",v"}
+										}
+									}
+								}
+							}
+						}
+					},
+					{"set": {"var": "$x", "val": 5}},
+					{"call_named": {"v": {"command": {"symbol": "+", "args": ["$x", 1]}}}}
+				]`,
+			expected: []any{5, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEvalWithMacros(t, tt.input)
+			testArrayObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestGensym(t *testing.T) {
+	input := `
+		[
+			{"command": {"symbol": "gensym", "args": "tmp"}},
+			{"command": {"symbol": "gensym", "args": "tmp"}},
+			{"command": {"symbol": "gensym"}}
+		]`
+
+	evaluated := testEvalWithMacros(t, input)
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	seen := make(map[string]bool)
+	for i, el := range array.Elements {
+		symbol, ok := el.(*object.String)
+		if !ok {
+			t.Fatalf("element %d is not String. got=%T", i, el)
+		}
+		if !strings.HasPrefix(symbol.Value, "$") {
+			t.Errorf("generated symbol must start with $. got=%s", symbol.Value)
+		}
+		if seen[symbol.Value] {
+			t.Errorf("generated symbol is not unique: %s", symbol.Value)
+		}
+		seen[symbol.Value] = true
+	}
+	if !strings.HasPrefix(array.Elements[0].Inspect(), "$tmp__") {
+		t.Errorf("generated symbol must start with the prefix. got=%s", array.Elements[0].Inspect())
+	}
+}
+
+func TestGensymSkipsWrittenSymbols(t *testing.T) {
+	// the variable of the user has the name which the hygienic macro would generate next
+	userVariable := "$tmp__1"
+	input := fmt.Sprintf(`
+		[
+			{
+				"defmacro": {
+					"name": "twice",
+					"keys": ["expr"],
+					"hygienic": true,
+					"body": {
+						"command": {
+							"symbol": "quote",
+							"args": [
+								{"set": {"var": "$tmp", "val": ",expr"}},
+								{"command": {"symbol": "+", "args": ["$tmp", "$tmp"]}}
+							]
+						}
+					}
+				}
+			},
+			{"set": {"var": "%[1]s", "val": 10}},
+			{"twice": {"expr": {"command": {"symbol": "+", "args": ["%[1]s", 1]}}}},
+			"%[1]s",
+			{"command": {"symbol": "gensym", "args": "tmp"}}
+		]`, userVariable)
+
+	evaluated := testEvalWithMacros(t, input)
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	testArrayObject(t, &object.Array{Elements: array.Elements[:3]}, []any{10, []any{11, 22}, 10})
+	if generated := array.Elements[3].Inspect(); generated == userVariable {
+		t.Errorf("gensym generated the symbol written in the program: %s", generated)
+	}
+}
+
+func TestMacroUnquoteSplice(t *testing.T) {
+	input := `
+		[
+			{
+				"defmacro": {
+					"name": "with_logging",
+					"keys": ["body"],
+					"body": {
+						"command": {
+							"symbol": "quote",
+							"args": ["start", ",@body", "end"]
+						}
+					}
+				}
+			},
+			{
+				"with_logging": {
+					"body": [
+						1,
+						{
+							"command": {
+								"symbol": "+",
+								"args": [1, 1]
+							}
+						}
+					]
+				}
+			}
+		]`
+
+	evaluated := testEvalWithMacros(t, input)
+	testArrayObject(t, evaluated, []any{[]any{"start", 1, 2, "end"}})
+}
+
+func TestMacroOptionalAndRestKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []any
+	}{
+		{
+			name: "optional key takes the default value",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "add",
+							"keys": ["x", {"key": "y", "default": 10}],
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": {"command": {"symbol": "+", "args": [",x", ",y"]}}
+								}
+							}
+						}
+					},
+					{"add": {"x": 1}}
+				]`,
+			expected: []any{11},
+		},
+		{
+			name: "optional key is overridden",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "add",
+							"keys": ["x", {"key": "y", "default": 10}],
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": {"command": {"symbol": "+", "args": [",x", ",y"]}}
+								}
+							}
+						}
+					},
+					{"add": {"x": 1, "y": 2}}
+				]`,
+			expected: []any{3},
+		},
+		{
+			name: "rest key collects undeclared keys",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "values",
+							"keys": ["first", "...rest"],
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": [",first", {"command": {"symbol": "at", "args": [{"map": ",rest"}, "b"]}}]
+								}
+							}
+						}
+					},
+					{"values": {"first": 1, "a": 2, "b": 3}}
+				]`,
+			expected: []any{[]any{1, 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEvalWithMacros(t, tt.input)
+			testArrayObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestExpandMacrosError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "missing required key",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "unless",
+							"keys": ["cond", "conseq"],
+							"body": {"command": {"symbol": "quote", "args": ",conseq"}}
+						}
+					},
+					{"unless": {"conseq": 1}}
+				]`,
+			expected: "macro unless: missing key cond at line 10, column 6",
+		},
+		{
+			name: "macro call with non object",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "unless",
+							"keys": ["cond"],
+							"body": {"command": {"symbol": "quote", "args": ",cond"}}
+						}
+					},
+					{"unless": 1}
+				]`,
+			expected: "macro unless: expects OBJECT, got 1 at line 10, column 6",
+		},
+		{
+			name: "macro body does not result in quote",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "one",
+							"body": 1
+						}
+					},
+					{"one": {}}
+				]`,
+			expected: "macro one: must result in QUOTE, got INTEGER at line 9, column 6",
+		},
+		{
+			name: "error in macro body",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "broken",
+							"body": {"command": {"symbol": "undefined_symbol"}}
+						}
+					},
+					{"broken": {}}
+				]`,
+			expected: "macro broken: symbol not found: undefined_symbol at line 9, column 6",
+		},
+		{
+			name: "unquote in object key",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "m",
+							"keys": ["k"],
+							"body": {"command": {"symbol": "quote", "args": {",k": 1}}}
+						}
+					},
+					{"m": {"k": {"command": {"symbol": "+", "args": [1]}}}}
+				]`,
+			expected: "macro m: unquote is not allowed in object keys: \",k\" at line 10, column 6",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := testParseProgram(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %s", err)
+			}
+
+			env := object.NewEnvironment()
+			if err := DefineMacros(program, env); err != nil {
+				t.Fatalf("define macro error: %s", err)
+			}
+			_, err = ExpandMacros(program, env)
+			_, ok := err.(*ExpansionError)
+			if !ok {
+				t.Fatalf("error is not ExpansionError. got=%T (%+v)", err, err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestNestedMacroDefinition(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []any
+	}{
+		{
+			name: "macro defined in nested array",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$f",
+							"val": {
+								"lambda": {
+									"params": "$x",
+									"body": [
+										{
+											"defmacro": {
+												"name": "double",
+												"keys": ["expr"],
+												"body": {"command": {"symbol": "quote", "args": {"command": {"symbol": "*", "args": [",expr", 2]}}}}
+											}
+										},
+										{"double": {"expr": "$x"}}
+									]
+								}
+							}
+						}
+					},
+					{"command": {"symbol": "$f", "args": 21}}
+				]`,
+			expected: []any{nil, []any{42}},
+		},
+		{
+			name: "macro expands into macro definition",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "defconst",
+							"keys": ["name", "value"],
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": {
+										"defmacro": {
+											"name": ",name",
+											"body": {"command": {"symbol": "quote", "args": ",value"}}
+										}
+									}
+								}
+							}
+						}
+					},
+					{"defconst": {"name": "answer", "value": 42}},
+					{"answer": {}}
+				]`,
+			expected: []any{42},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEvalWithMacros(t, tt.input)
+			testArrayObject(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestNestedMacroVisibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name: "nested macro is not visible outside",
+			input: `
+				[
+					[
+						{
+							"defmacro": {
+								"name": "one",
+								"body": {"command": {"symbol": "quote", "args": 1}}
+							}
+						},
+						{"one": {}}
+					],
+					{"one": {}}
+				]`,
+			expected: `unknown key for object: {"one": {}}`,
+		},
+		{
+			name: "nested macro shadows outer macro",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "m",
+							"body": {"command": {"symbol": "quote", "args": "outer"}}
+						}
+					},
+					[
+						{
+							"defmacro": {
+								"name": "m",
+								"body": {"command": {"symbol": "quote", "args": "inner"}}
+							}
+						},
+						{"m": {}}
+					],
+					{"m": {}}
+				]`,
+			expected: []any{[]any{"inner"}, "outer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEvalWithMacros(t, tt.input)
+			switch expected := tt.expected.(type) {
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+			case []any:
+				testArrayObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestMacroExpansionLimit(t *testing.T) {
+	input := `
+		[
+			{
+				"defmacro": {
+					"name": "forever",
+					"body": {"command": {"symbol": "quote", "args": {"forever": {}}}}
+				}
+			},
+			{"forever": {}}
+		]`
+
+	program, err := testParseProgram(input)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+	_, err = ExpandMacros(program, object.NewEnvironment())
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	expected := "macro forever: exceeds the expansion depth limit of 1000 at line 6, column 54"
+	if err.Error() != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestMacroexpand(t *testing.T) {
+	definitions := `
+		{
+			"defmacro": {
+				"name": "inc",
+				"keys": ["x"],
+				"body": {"command": {"symbol": "quote", "args": {"command": {"symbol": "+", "args": [",x", 1]}}}}
+			}
+		},
+		{
+			"defmacro": {
+				"name": "double_inc",
+				"keys": ["x"],
+				"body": {"command": {"symbol": "quote", "args": {"inc": {"x": {"command": {"symbol": "*", "args": [",x", 2]}}}}}}
+			}
+		},`
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "expand once",
+			input:    `{"command": {"symbol": "macroexpand-1", "args": {"command": {"symbol": "quote", "args": {"double_inc": {"x": 1}}}}}}`,
+			expected: `{"inc": {"x": {"command": {"symbol": "*", "args": [1, 2]}}}}`,
+		},
+		{
+			name:     "expand fully",
+			input:    `{"command": {"symbol": "macroexpand", "args": {"command": {"symbol": "quote", "args": {"double_inc": {"x": 1}}}}}}`,
+			expected: `{"command": {"symbol": "+", "args": [{"command": {"symbol": "*", "args": [1, 2]}}, 1]}}`,
+		},
+		{
+			name:     "expand non macro call",
+			input:    `{"command": {"symbol": "macroexpand-1", "args": {"command": {"symbol": "quote", "args": {"command": {"symbol": "+", "args": [1, 2]}}}}}}`,
+			expected: `{"command": {"symbol": "+", "args": [1, 2]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEvalWithMacros(t, "["+definitions+tt.input+"]")
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+			}
+			quote, ok := array.Elements[0].(*object.Quote)
+			if !ok {
+				t.Fatalf("object is not Quote. got=%T (%+v)", array.Elements[0], array.Elements[0])
+			}
+			if quote.Inspect() != tt.expected {
+				t.Errorf("not equal. got=%q, want=%q", quote.Inspect(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestMacroexpandError(t *testing.T) {
+	evaluated := testEval(t, `{"command": {"symbol": "macroexpand", "args": 1}}`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "argument to 'macroexpand' must be QUOTE, got INTEGER"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestExpandMacrosDoesNotModifyProgram(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "macro",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "inc",
+							"keys": ["x"],
+							"body": {"command": {"symbol": "quote", "args": {"command": {"symbol": "+", "args": [",x", 1]}}}}
+						}
+					},
+					{"inc": {"x": 1}},
+					{"inc": {"x": {"inc": {"x": 10}}}}
+				]`,
+			expected: `[{"command": {"symbol": "+", "args": [1, 1]}}, {"command": {"symbol": "+", "args": [{"command": {"symbol": "+", "args": [10, 1]}}, 1]}}]`,
+		},
+		{
+			name: "hygienic macro",
+			input: `
+				[
+					{
+						"defmacro": {
+							"name": "twice",
+							"keys": ["x"],
+							"hygienic": true,
+							"body": {
+								"command": {
+									"symbol": "quote",
+									"args": [
+										{"set": {"var": "$tmp", "val": ",x"}},
+										{"command": {"symbol": "+", "args": ["$tmp", "$tmp"]}}
+									]
+								}
+							}
+						}
+					},
+					{"twice": {"x": 1}},
+					{"twice": {"x": 2}}
+				]`,
+			expected: `[[{"set": {"var": "$tmp__1", "val": 1}}, {"command": {"symbol": "+", "args": ["$tmp__1", "$tmp__1"]}}], [{"set": {"var": "$tmp__2", "val": 2}}, {"command": {"symbol": "+", "args": ["$tmp__2", "$tmp__2"]}}]]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := testParseProgram(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %s", err)
+			}
+			source := program.String()
+
+			// the same program is expanded twice, e.g. in a REPL or with a cached module
+			for i := 0; i < 2; i++ {
+				env := object.NewEnvironment()
+				if err := DefineMacros(program, env); err != nil {
+					t.Fatalf("define macro error: %s", err)
+				}
+				expanded, err := ExpandMacros(program, env)
+				if err != nil {
+					t.Fatalf("expand macro error: %s", err)
+				}
+
+				if expanded.String() != tt.expected {
+					t.Errorf("not equal. got=%q, want=%q", expanded.String(), tt.expected)
+				}
+				if program.String() != source {
+					t.Errorf("program is modified. got=%q, want=%q", program.String(), source)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JunNishimura/jsop/ast"
+	"github.com/JunNishimura/jsop/object"
+)
+
+const (
+	wildcardSymbol = "$_"
+	restPrefix     = "$..."
+)
+
+func evalMatchExpression(exp ast.Expression, env *object.Environment) object.Object {
+	keyValueObj, ok := exp.(*ast.KeyValueObject)
+	if !ok {
+		return newError("invalid value for match: %s", exp)
+	}
+	kvPairs := keyValueObj.KVPairs()
+
+	valueValue, ok := kvPairs["value"]
+	if !ok {
+		return newError("value key not found in match: %s", keyValueObj)
+	}
+	value := Eval(valueValue, env)
+	if isError(value) {
+		return value
+	}
+
+	clausesValue, ok := kvPairs["clauses"]
+	if !ok {
+		return newError("clauses key not found in match: %s", keyValueObj)
+	}
+	clauses, ok := clausesValue.(*ast.Array)
+	if !ok {
+		return newError("clauses key must be ARRAY, got %s", clausesValue)
+	}
+
+	for _, clause := range clauses.Elements {
+		clauseObj, ok := clause.(*ast.KeyValueObject)
+		if !ok {
+			return newError("clause in match must be OBJECT, got %s", clause)
+		}
+		clausePairs := clauseObj.KVPairs()
+
+		pattern, ok := clausePairs["pattern"]
+		if !ok {
+			return newError("pattern key not found in clause: %s", clauseObj)
+		}
+		thenValue, ok := clausePairs["then"]
+		if !ok {
+			return newError("then key not found in clause: %s", clauseObj)
+		}
+		if _, err := patternVariables(pattern); err != nil {
+			return newError("invalid pattern %s: %s", pattern, err)
+		}
+
+		extendedEnv := object.NewEnclosedEnvironment(env)
+		matched, err := matchPattern(pattern, value, extendedEnv)
+		if err != nil {
+			return newError("invalid pattern %s: %s", pattern, err)
+		}
+		if !matched {
+			continue
+		}
+
+		if guardValue, ok := clausePairs["guard"]; ok {
+			guard := Eval(guardValue, extendedEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(thenValue, extendedEnv)
+	}
+
+	return newError("no clause matched in match: %s", value.Inspect())
+}
+
+// matchPattern reports whether the value matches the pattern,
+// and binds the variables in the pattern to env when it matches.
+func matchPattern(pattern ast.Expression, value object.Object, env *object.Environment) (bool, error) {
+	switch pattern := pattern.(type) {
+	case *ast.StringLiteral:
+		if !strings.HasPrefix(pattern.Value, "$") {
+			strValue, ok := value.(*object.String)
+			return ok && strValue.Value == pattern.Value, nil
+		}
+		if pattern.Value == wildcardSymbol {
+			return true, nil
+		}
+		if strings.HasPrefix(pattern.Value, restPrefix) {
+			return false, fmt.Errorf("rest pattern must be the last element of array pattern: %s", pattern.Value)
+		}
+		env.Define(pattern.Value, value)
+		return true, nil
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.FloatLiteral, *ast.Boolean, *ast.PrefixAtom:
+		literal := Eval(pattern, env)
+		if isError(literal) {
+			return false, fmt.Errorf("%s", literal.(*object.Error).Message)
+		}
+		return object.Equal(literal, value), nil
+	case *ast.Array:
+		return matchArrayPattern(pattern, value, env)
+	case *ast.KeyValueObject:
+		return matchMapPattern(pattern, value, env)
+	default:
+		return false, fmt.Errorf("unknown pattern type: %T", pattern)
+	}
+}
+
+func matchArrayPattern(pattern *ast.Array, value object.Object, env *object.Environment) (bool, error) {
+	arrayValue, ok := value.(*object.Array)
+	if !ok {
+		return false, nil
+	}
+
+	elements := pattern.Elements
+	var rest *ast.StringLiteral
+	if len(elements) > 0 {
+		if last, ok := elements[len(elements)-1].(*ast.StringLiteral); ok && strings.HasPrefix(last.Value, restPrefix) {
+			rest = last
+			elements = elements[:len(elements)-1]
+		}
+	}
+
+	if rest == nil && len(arrayValue.Elements) != len(elements) {
+		return false, nil
+	}
+	if rest != nil && len(arrayValue.Elements) < len(elements) {
+		return false, nil
+	}
+
+	for i, el := range elements {
+		matched, err := matchPattern(el, arrayValue.Elements[i], env)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	if rest != nil {
+		restElements := make([]object.Object, len(arrayValue.Elements)-len(elements))
+		copy(restElements, arrayValue.Elements[len(elements):])
+		env.Define(restVariable(rest.Value), &object.Array{Elements: restElements})
+	}
+
+	return true, nil
+}
+
+func matchMapPattern(pattern *ast.KeyValueObject, value object.Object, env *object.Environment) (bool, error) {
+	mapPattern, ok := pattern.KVPairs()["map"]
+	if !ok || len(pattern.KV) != 1 {
+		return false, fmt.Errorf("object pattern must be map: %s", pattern)
+	}
+	mapPatternObj, ok := mapPattern.(*ast.KeyValueObject)
+	if !ok {
+		return false, fmt.Errorf("map pattern must be OBJECT, got %s", mapPattern)
+	}
+
+	mapValue, ok := value.(*object.Map)
+	if !ok {
+		return false, nil
+	}
+
+	for _, kv := range mapPatternObj.KV {
+		pairValue, ok := mapValue.Get(mapKey(kv.Key))
+		if !ok {
+			return false, nil
+		}
+		matched, err := matchPattern(kv.Value, pairValue, env)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// patternVariables walks through the pattern and returns the variables bound by it.
+// it reports an error if the same variable is bound more than once.
+func patternVariables(pattern ast.Expression) ([]string, error) {
+	var err error
+	variables := make([]string, 0)
+	bound := make(map[string]bool)
+
+	ast.Inspect(pattern, func(exp ast.Expression) bool {
+		strLit, ok := exp.(*ast.StringLiteral)
+		if !ok || !strings.HasPrefix(strLit.Value, "$") || strLit.Value == wildcardSymbol {
+			return true
+		}
+
+		name := strLit.Value
+		if strings.HasPrefix(name, restPrefix) {
+			name = restVariable(name)
+		}
+		if bound[name] && err == nil {
+			err = fmt.Errorf("variable %s is bound more than once", name)
+		}
+		if !bound[name] {
+			variables = append(variables, name)
+		}
+		bound[name] = true
+
+		return true
+	})
+
+	return variables, err
+}
+
+// restVariable returns the variable name of the rest pattern. e.g. "$...rest" -> "$rest"
+func restVariable(rest string) string {
+	return "$" + strings.TrimPrefix(rest, restPrefix)
+}
@@ -0,0 +1,304 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name: "match literal",
+			input: `
+				{
+					"match": {
+						"value": 2,
+						"clauses": [
+							{
+								"pattern": 1,
+								"then": "one"
+							},
+							{
+								"pattern": 2,
+								"then": "two"
+							}
+						]
+					}
+				}`,
+			expected: "two",
+		},
+		{
+			name: "match negative integer and string literal",
+			input: `
+				{
+					"match": {
+						"value": [-1, "ok"],
+						"clauses": [
+							{
+								"pattern": [-1, "ng"],
+								"then": 1
+							},
+							{
+								"pattern": [-1, "ok"],
+								"then": 2
+							}
+						]
+					}
+				}`,
+			expected: 2,
+		},
+		{
+			name: "bind variable",
+			input: `
+				{
+					"match": {
+						"value": 10,
+						"clauses": [
+							{
+								"pattern": "$x",
+								"then": {
+									"command": {
+										"symbol": "+",
+										"args": ["$x", 1]
+									}
+								}
+							}
+						]
+					}
+				}`,
+			expected: 11,
+		},
+		{
+			name: "destructure array with head and rest",
+			input: `
+				{
+					"match": {
+						"value": [1, 2, 3],
+						"clauses": [
+							{
+								"pattern": ["$head", "$...rest"],
+								"then": {
+									"command": {
+										"symbol": "+",
+										"args": [
+											"$head",
+											{
+												"command": {
+													"symbol": "len",
+													"args": "$rest"
+												}
+											}
+										]
+									}
+								}
+							}
+						]
+					}
+				}`,
+			expected: 3,
+		},
+		{
+			name: "array length must match without rest",
+			input: `
+				{
+					"match": {
+						"value": [1, 2, 3],
+						"clauses": [
+							{
+								"pattern": ["$a", "$b"],
+								"then": 1
+							},
+							{
+								"pattern": ["$a", "$_", "$c"],
+								"then": "$c"
+							}
+						]
+					}
+				}`,
+			expected: 3,
+		},
+		{
+			name: "destructure nested map",
+			input: `
+				{
+					"match": {
+						"value": {
+							"map": {
+								"user": {
+									"map": {
+										"name": "Alice",
+										"tags": ["admin", "dev"]
+									}
+								},
+								"active": true
+							}
+						},
+						"clauses": [
+							{
+								"pattern": {
+									"map": {
+										"active": false
+									}
+								},
+								"then": "inactive"
+							},
+							{
+								"pattern": {
+									"map": {
+										"user": {
+											"map": {
+												"name": "$name",
+												"tags": ["$first", "$..._"]
+											}
+										}
+									}
+								},
+								"then": "{$name}: {$first}"
+							}
+						]
+					}
+				}`,
+			expected: "Alice: admin",
+		},
+		{
+			name: "guard expression",
+			input: `
+				{
+					"match": {
+						"value": 5,
+						"clauses": [
+							{
+								"pattern": "$n",
+								"guard": {
+									"command": {
+										"symbol": ">",
+										"args": ["$n", 10]
+									}
+								},
+								"then": "large"
+							},
+							{
+								"pattern": "$n",
+								"then": "small"
+							}
+						]
+					}
+				}`,
+			expected: "small",
+		},
+		{
+			name: "bindings do not leak to outer environment",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$x",
+							"val": 1
+						}
+					},
+					{
+						"match": {
+							"value": 100,
+							"clauses": [
+								{
+									"pattern": "$x",
+									"then": "$x"
+								}
+							]
+						}
+					},
+					"$x"
+				]`,
+			expected: []any{1, 100, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				testStringObject(t, evaluated, expected)
+			case []any:
+				testArrayObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestMatchExpressionError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "no clause matches",
+			input: `
+				{
+					"match": {
+						"value": 3,
+						"clauses": [
+							{
+								"pattern": 1,
+								"then": "one"
+							}
+						]
+					}
+				}`,
+			expected: "no clause matched in match: 3",
+		},
+		{
+			name: "variable bound more than once",
+			input: `
+				{
+					"match": {
+						"value": [1, 1],
+						"clauses": [
+							{
+								"pattern": ["$x", "$x"],
+								"then": "$x"
+							}
+						]
+					}
+				}`,
+			expected: "invalid pattern [\"$x\", \"$x\"]: variable $x is bound more than once",
+		},
+		{
+			name: "rest pattern not at the end",
+			input: `
+				{
+					"match": {
+						"value": [1, 2],
+						"clauses": [
+							{
+								"pattern": ["$...rest", "$x"],
+								"then": "$x"
+							}
+						]
+					}
+				}`,
+			expected: "invalid pattern [\"$...rest\", \"$x\"]: rest pattern must be the last element of array pattern: $...rest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
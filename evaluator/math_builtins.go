@@ -0,0 +1,397 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+// maxIntegerBits limits the size of integers made by 'pow' and '<<',
+// which otherwise could exhaust the memory or take forever with a large exponent or shift count.
+const maxIntegerBits = 1 << 20
+
+// randomSource is shared by random builtins so that random_seed makes the sequence deterministic.
+var randomSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+var mathBuiltins = map[string]*object.Builtin{
+	"abs": {
+		Fn: func(args object.Object) object.Object {
+			switch args := args.(type) {
+			case *object.Integer, *object.BigInt:
+				if toBigInt(args).Sign() < 0 {
+					return evalIntegerArithmetic("-", &object.Integer{Value: 0}, args)
+				}
+				return args
+			case *object.Float:
+				return &object.Float{Value: math.Abs(args.Value)}
+			default:
+				return newError("argument to 'abs' must be NUMBER, got %s", args.Type())
+			}
+		},
+		Params: []string{"$value"},
+	},
+	"sqrt": {
+		Fn: func(args object.Object) object.Object {
+			if !isNumber(args) {
+				return newError("argument to 'sqrt' must be NUMBER, got %s", args.Type())
+			}
+			value := toFloat(args)
+			if value < 0 {
+				return newError("square root of negative number: %s", args.Inspect())
+			}
+			return &object.Float{Value: math.Sqrt(value)}
+		},
+		Params: []string{"$value"},
+	},
+	"floor": {
+		Fn: func(args object.Object) object.Object {
+			return roundNumber("floor", args, math.Floor)
+		},
+		Params: []string{"$value"},
+	},
+	"ceil": {
+		Fn: func(args object.Object) object.Object {
+			return roundNumber("ceil", args, math.Ceil)
+		},
+		Params: []string{"$value"},
+	},
+	"round": {
+		Fn: func(args object.Object) object.Object {
+			return roundNumber("round", args, math.Round)
+		},
+		Params: []string{"$value"},
+	},
+	"pow": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, err := numberArguments("pow", args)
+			if err != nil {
+				return err
+			}
+			if len(arrayArg) != 2 {
+				return newError("number of arguments to 'pow' must be 2, got %d", len(arrayArg))
+			}
+
+			base, exponent := arrayArg[0], arrayArg[1]
+			if !isInteger(base) || !isInteger(exponent) || toBigInt(exponent).Sign() < 0 {
+				return &object.Float{Value: math.Pow(toFloat(base), toFloat(exponent))}
+			}
+
+			// the result has at least (bits of base - 1) * exponent bits, so it is too large above the limit.
+			// the exponent does not matter when the base is 0, 1 or -1.
+			baseBits := new(big.Int).Abs(toBigInt(base)).BitLen()
+			smallExponent, ok := exponent.(*object.Integer)
+			if baseBits > 1 && (!ok || smallExponent.Value > maxIntegerBits || int64(baseBits-1)*smallExponent.Value > maxIntegerBits) {
+				return newError("result of 'pow' exceeds the limit of %d bits: pow(%s, %s)", maxIntegerBits, base.Inspect(), exponent.Inspect())
+			}
+
+			result := new(big.Int).Exp(toBigInt(base), toBigInt(exponent), nil)
+			if !result.IsInt64() && overflowMode == OverflowError {
+				return newError("integer overflow: pow(%s, %s)", base.Inspect(), exponent.Inspect())
+			}
+			return normalizeBigInt(result)
+		},
+		Params: []string{"$base", "$exponent"},
+	},
+	"clamp": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, err := numberArguments("clamp", args)
+			if err != nil {
+				return err
+			}
+			if len(arrayArg) != 3 {
+				return newError("number of arguments to 'clamp' must be 3, got %d", len(arrayArg))
+			}
+
+			value, lower, upper := arrayArg[0], arrayArg[1], arrayArg[2]
+			if compareNumbers(lower, upper) > 0 {
+				return newError("min of 'clamp' must not be greater than max, got min=%s, max=%s", lower.Inspect(), upper.Inspect())
+			}
+			if compareNumbers(value, lower) < 0 {
+				return lower
+			}
+			if compareNumbers(value, upper) > 0 {
+				return upper
+			}
+			return value
+		},
+		Params: []string{"$value", "$min", "$max"},
+	},
+	"min": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, err := numberArguments("min", args)
+			if err != nil {
+				return err
+			}
+			if len(arrayArg) == 0 {
+				return newError("number of arguments to 'min' must be more than 0, got %d", len(arrayArg))
+			}
+
+			result := arrayArg[0]
+			for _, arg := range arrayArg[1:] {
+				if compareNumbers(arg, result) < 0 {
+					result = arg
+				}
+			}
+			return result
+		},
+	},
+	"max": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, err := numberArguments("max", args)
+			if err != nil {
+				return err
+			}
+			if len(arrayArg) == 0 {
+				return newError("number of arguments to 'max' must be more than 0, got %d", len(arrayArg))
+			}
+
+			result := arrayArg[0]
+			for _, arg := range arrayArg[1:] {
+				if compareNumbers(arg, result) > 0 {
+					result = arg
+				}
+			}
+			return result
+		},
+	},
+	"sum": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, err := numberArguments("sum", args)
+			if err != nil {
+				return err
+			}
+			return sumNumbers(arrayArg)
+		},
+	},
+	"avg": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, err := numberArguments("avg", args)
+			if err != nil {
+				return err
+			}
+			if len(arrayArg) == 0 {
+				return newError("number of arguments to 'avg' must be more than 0, got %d", len(arrayArg))
+			}
+
+			sum := sumNumbers(arrayArg)
+			if isError(sum) {
+				return sum
+			}
+			return &object.Float{Value: toFloat(sum) / float64(len(arrayArg))}
+		},
+	},
+	"gcd": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, err := integerArguments("gcd", args)
+			if err != nil {
+				return err
+			}
+			if len(arrayArg) == 0 {
+				return newError("number of arguments to 'gcd' must be more than 0, got %d", len(arrayArg))
+			}
+
+			result := new(big.Int)
+			for _, arg := range arrayArg {
+				result.GCD(nil, nil, result, new(big.Int).Abs(toBigInt(arg)))
+			}
+			return normalizeBigInt(result)
+		},
+	},
+	"&": {
+		Fn: func(args object.Object) object.Object {
+			return evalBitwiseOperation("&", args, (*big.Int).And)
+		},
+	},
+	"|": {
+		Fn: func(args object.Object) object.Object {
+			return evalBitwiseOperation("|", args, (*big.Int).Or)
+		},
+	},
+	"^": {
+		Fn: func(args object.Object) object.Object {
+			return evalBitwiseOperation("^", args, (*big.Int).Xor)
+		},
+	},
+	"~": {
+		Fn: func(args object.Object) object.Object {
+			if !isInteger(args) {
+				return newError("argument to '~' must be INTEGER, got %s", args.Type())
+			}
+			return normalizeBigInt(new(big.Int).Not(toBigInt(args)))
+		},
+		Params: []string{"$value"},
+	},
+	"<<": {
+		Fn: func(args object.Object) object.Object {
+			value, count, err := shiftArguments("<<", args)
+			if err != nil {
+				return err
+			}
+
+			result := new(big.Int).Lsh(toBigInt(value), count)
+			if !result.IsInt64() && overflowMode == OverflowError {
+				return newError("integer overflow: %s << %d", value.Inspect(), count)
+			}
+			return normalizeBigInt(result)
+		},
+		Params: []string{"$value", "$count"},
+	},
+	">>": {
+		Fn: func(args object.Object) object.Object {
+			value, count, err := shiftArguments(">>", args)
+			if err != nil {
+				return err
+			}
+			return normalizeBigInt(new(big.Int).Rsh(toBigInt(value), count))
+		},
+		Params: []string{"$value", "$count"},
+	},
+	"random": {
+		Fn: func(args object.Object) object.Object {
+			if !hasNoArguments(args) {
+				return newError("'random' takes no arguments, got %s", args.Inspect())
+			}
+			return &object.Float{Value: randomSource.Float64()}
+		},
+	},
+	"random_int": {
+		Fn: func(args object.Object) object.Object {
+			arrayArg, ok := args.(*object.Array)
+			if !ok {
+				return newError("argument to 'random_int' must be ARRAY, got %s", args.Type())
+			}
+			if len(arrayArg.Elements) != 2 {
+				return newError("number of arguments to 'random_int' must be 2, got %d", len(arrayArg.Elements))
+			}
+			lower, ok := arrayArg.Elements[0].(*object.Integer)
+			if !ok {
+				return newError("first argument to 'random_int' must be INTEGER, got %s", arrayArg.Elements[0].Type())
+			}
+			upper, ok := arrayArg.Elements[1].(*object.Integer)
+			if !ok {
+				return newError("second argument to 'random_int' must be INTEGER, got %s", arrayArg.Elements[1].Type())
+			}
+			if lower.Value > upper.Value {
+				return newError("min of 'random_int' must not be greater than max, got min=%d, max=%d", lower.Value, upper.Value)
+			}
+
+			// the range is inclusive, and computed in big.Int not to overflow with extreme bounds
+			span := new(big.Int).Sub(big.NewInt(upper.Value), big.NewInt(lower.Value))
+			span.Add(span, big.NewInt(1))
+			offset := new(big.Int).Rand(randomSource, span)
+			return normalizeBigInt(offset.Add(offset, big.NewInt(lower.Value)))
+		},
+		Params: []string{"$min", "$max"},
+	},
+	"random_seed": {
+		Fn: func(args object.Object) object.Object {
+			seed, ok := args.(*object.Integer)
+			if !ok {
+				return newError("argument to 'random_seed' must be INTEGER, got %s", args.Type())
+			}
+			randomSource.Seed(seed.Value)
+
+			return Null
+		},
+		Params: []string{"$seed"},
+	},
+}
+
+func init() {
+	for name, builtin := range mathBuiltins {
+		builtins[name] = builtin
+	}
+}
+
+func numberArguments(name string, args object.Object) ([]object.Object, *object.Error) {
+	arrayArg, ok := args.(*object.Array)
+	if !ok {
+		return nil, newError("argument to '%s' must be ARRAY, got %s", name, args.Type())
+	}
+	for _, arg := range arrayArg.Elements {
+		if !isNumber(arg) {
+			return nil, newError("argument to '%s' must be NUMBER, got %s", name, arg.Type())
+		}
+	}
+	return arrayArg.Elements, nil
+}
+
+func integerArguments(name string, args object.Object) ([]object.Object, *object.Error) {
+	arrayArg, ok := args.(*object.Array)
+	if !ok {
+		return nil, newError("argument to '%s' must be ARRAY, got %s", name, args.Type())
+	}
+	for _, arg := range arrayArg.Elements {
+		if !isInteger(arg) {
+			return nil, newError("argument to '%s' must be INTEGER, got %s", name, arg.Type())
+		}
+	}
+	return arrayArg.Elements, nil
+}
+
+func sumNumbers(numbers []object.Object) object.Object {
+	var result object.Object = &object.Integer{Value: 0}
+	for _, number := range numbers {
+		result = evalNumberArithmetic("+", result, number)
+		if isError(result) {
+			return result
+		}
+	}
+	return result
+}
+
+// roundNumber rounds the float to an integer by the given function. integers are returned as they are.
+func roundNumber(name string, value object.Object, round func(float64) float64) object.Object {
+	if isInteger(value) {
+		return value
+	}
+	floatValue, ok := value.(*object.Float)
+	if !ok {
+		return newError("argument to '%s' must be NUMBER, got %s", name, value.Type())
+	}
+
+	rounded := round(floatValue.Value)
+	if math.IsNaN(rounded) || math.IsInf(rounded, 0) {
+		return newError("cannot convert %s to integer", floatValue.Inspect())
+	}
+	result, _ := big.NewFloat(rounded).Int(nil)
+	return normalizeBigInt(result)
+}
+
+func evalBitwiseOperation(name string, args object.Object, operate func(z, x, y *big.Int) *big.Int) object.Object {
+	arrayArg, err := integerArguments(name, args)
+	if err != nil {
+		return err
+	}
+	if len(arrayArg) <= 1 {
+		return newError("number of arguments to '%s' must be more than 1, got %d", name, len(arrayArg))
+	}
+
+	result := new(big.Int).Set(toBigInt(arrayArg[0]))
+	for _, arg := range arrayArg[1:] {
+		operate(result, result, toBigInt(arg))
+	}
+	return normalizeBigInt(result)
+}
+
+func shiftArguments(name string, args object.Object) (object.Object, uint, *object.Error) {
+	arrayArg, err := integerArguments(name, args)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(arrayArg) != 2 {
+		return nil, 0, newError("number of arguments to '%s' must be 2, got %d", name, len(arrayArg))
+	}
+
+	count, ok := arrayArg[1].(*object.Integer)
+	if !ok || count.Value < 0 {
+		return nil, 0, newError("shift count of '%s' must be non-negative INTEGER, got %s", name, arrayArg[1].Inspect())
+	}
+	if count.Value > maxIntegerBits {
+		return nil, 0, newError("shift count of '%s' must not exceed %d, got %d", name, maxIntegerBits, count.Value)
+	}
+	return arrayArg[0], uint(count.Value), nil
+}
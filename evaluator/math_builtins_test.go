@@ -0,0 +1,419 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Fatalf("object is not Float. got=%T (%+v)", obj, obj)
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+	}
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name:     "float literal",
+			input:    "1.5",
+			expected: 1.5,
+		},
+		{
+			name:     "negative float",
+			input:    "-2.5e2",
+			expected: -250.0,
+		},
+		{
+			name: "integer and float are added as float",
+			input: `
+				{
+					"command": {
+						"symbol": "+",
+						"args": [1, 0.5]
+					}
+				}`,
+			expected: 1.5,
+		},
+		{
+			name: "float division",
+			input: `
+				{
+					"command": {
+						"symbol": "/",
+						"args": [7.0, 2]
+					}
+				}`,
+			expected: 3.5,
+		},
+		{
+			name: "float modulo",
+			input: `
+				{
+					"command": {
+						"symbol": "%",
+						"args": [5.5, 2]
+					}
+				}`,
+			expected: 1.5,
+		},
+		{
+			name: "compare integer and float",
+			input: `
+				{
+					"command": {
+						"symbol": "<",
+						"args": [1, 1.5, 2]
+					}
+				}`,
+			expected: true,
+		},
+		{
+			name: "integer and float with the same value are equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": [1, 1.0]
+					}
+				}`,
+			expected: true,
+		},
+		{
+			name: "big integer and float with the same value are equal",
+			input: `
+				{
+					"command": {
+						"symbol": "==",
+						"args": [18446744073709551616, 1.8446744073709551616e19]
+					}
+				}`,
+			expected: true,
+		},
+		{
+			name: "integer and float with different values are not equal",
+			input: `
+				{
+					"command": {
+						"symbol": "!=",
+						"args": [9007199254740993, 9007199254740992.0]
+					}
+				}`,
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			switch expected := tt.expected.(type) {
+			case float64:
+				testFloatObject(t, evaluated, expected)
+			case bool:
+				testBooleanObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{
+			name:     "abs of integer",
+			input:    `{"command": {"symbol": "abs", "args": -5}}`,
+			expected: int64(5),
+		},
+		{
+			name:     "abs of float",
+			input:    `{"command": {"symbol": "abs", "args": -1.5}}`,
+			expected: 1.5,
+		},
+		{
+			name:     "abs of min int64 is promoted",
+			input:    `{"command": {"symbol": "abs", "args": -9223372036854775808}}`,
+			expected: "9223372036854775808",
+		},
+		{
+			name:     "min of mixed numbers",
+			input:    `{"command": {"symbol": "min", "args": [3, 1.5, 2]}}`,
+			expected: 1.5,
+		},
+		{
+			name: "max of array variable",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$scores",
+							"val": [72, 95, 88]
+						}
+					},
+					{
+						"command": {
+							"symbol": "max",
+							"args": ["$...scores"]
+						}
+					}
+				]`,
+			expected: []any{[]any{72, 95, 88}, 95},
+		},
+		{
+			name:     "pow of integers",
+			input:    `{"command": {"symbol": "pow", "args": [2, 10]}}`,
+			expected: int64(1024),
+		},
+		{
+			name:     "pow overflows to big integer",
+			input:    `{"command": {"symbol": "pow", "args": [2, 64]}}`,
+			expected: "18446744073709551616",
+		},
+		{
+			name:     "pow with negative exponent",
+			input:    `{"command": {"symbol": "pow", "args": [2, -1]}}`,
+			expected: 0.5,
+		},
+		{
+			name:     "pow of one with huge exponent",
+			input:    `{"command": {"symbol": "pow", "args": [-1, 100000000001]}}`,
+			expected: int64(-1),
+		},
+		{
+			name:     "sqrt",
+			input:    `{"command": {"symbol": "sqrt", "args": 16}}`,
+			expected: 4.0,
+		},
+		{
+			name:     "floor",
+			input:    `{"command": {"symbol": "floor", "args": -1.5}}`,
+			expected: int64(-2),
+		},
+		{
+			name:     "ceil",
+			input:    `{"command": {"symbol": "ceil", "args": 1.2}}`,
+			expected: int64(2),
+		},
+		{
+			name:     "round half away from zero",
+			input:    `{"command": {"symbol": "round", "args": 2.5}}`,
+			expected: int64(3),
+		},
+		{
+			name:     "round integer",
+			input:    `{"command": {"symbol": "round", "args": 7}}`,
+			expected: int64(7),
+		},
+		{
+			name:     "clamp below min",
+			input:    `{"command": {"symbol": "clamp", "args": [-3, 0, 10]}}`,
+			expected: int64(0),
+		},
+		{
+			name:     "clamp with named arguments",
+			input:    `{"command": {"symbol": "clamp", "args": {"$value": 12, "$min": 0, "$max": 10}}}`,
+			expected: int64(10),
+		},
+		{
+			name:     "sum",
+			input:    `{"command": {"symbol": "sum", "args": [1, 2, 3, 4]}}`,
+			expected: int64(10),
+		},
+		{
+			name:     "sum of empty array",
+			input:    `{"command": {"symbol": "sum", "args": []}}`,
+			expected: int64(0),
+		},
+		{
+			name:     "avg",
+			input:    `{"command": {"symbol": "avg", "args": [1, 2, 3, 4]}}`,
+			expected: 2.5,
+		},
+		{
+			name:     "gcd",
+			input:    `{"command": {"symbol": "gcd", "args": [12, -18, 30]}}`,
+			expected: int64(6),
+		},
+		{
+			name:     "bitwise and",
+			input:    `{"command": {"symbol": "&", "args": [12, 10]}}`,
+			expected: int64(8),
+		},
+		{
+			name:     "bitwise or",
+			input:    `{"command": {"symbol": "|", "args": [12, 10]}}`,
+			expected: int64(14),
+		},
+		{
+			name:     "bitwise xor",
+			input:    `{"command": {"symbol": "^", "args": [12, 10]}}`,
+			expected: int64(6),
+		},
+		{
+			name:     "bitwise not",
+			input:    `{"command": {"symbol": "~", "args": 5}}`,
+			expected: int64(-6),
+		},
+		{
+			name:     "shift left",
+			input:    `{"command": {"symbol": "<<", "args": [1, 4]}}`,
+			expected: int64(16),
+		},
+		{
+			name:     "shift right",
+			input:    `{"command": {"symbol": ">>", "args": [-16, 2]}}`,
+			expected: int64(-4),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			switch expected := tt.expected.(type) {
+			case int64:
+				testIntegerObject(t, evaluated, expected)
+			case float64:
+				testFloatObject(t, evaluated, expected)
+			case string:
+				testBigIntObject(t, evaluated, expected)
+			case []any:
+				testArrayObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestMathBuiltinsError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "min of empty array",
+			input:    `{"command": {"symbol": "min", "args": []}}`,
+			expected: "number of arguments to 'min' must be more than 0, got 0",
+		},
+		{
+			name:     "max of non number",
+			input:    `{"command": {"symbol": "max", "args": [1, "two"]}}`,
+			expected: "argument to 'max' must be NUMBER, got STRING",
+		},
+		{
+			name:     "random with arguments",
+			input:    `{"command": {"symbol": "random", "args": [1]}}`,
+			expected: "'random' takes no arguments, got [1]",
+		},
+		{
+			name:     "sqrt of negative number",
+			input:    `{"command": {"symbol": "sqrt", "args": -4}}`,
+			expected: "square root of negative number: -4",
+		},
+		{
+			name:     "clamp with min greater than max",
+			input:    `{"command": {"symbol": "clamp", "args": [1, 10, 0]}}`,
+			expected: "min of 'clamp' must not be greater than max, got min=10, max=0",
+		},
+		{
+			name:     "bitwise operation on float",
+			input:    `{"command": {"symbol": "&", "args": [1.5, 1]}}`,
+			expected: "argument to '&' must be INTEGER, got FLOAT",
+		},
+		{
+			name:     "negative shift count",
+			input:    `{"command": {"symbol": "<<", "args": [1, -1]}}`,
+			expected: "shift count of '<<' must be non-negative INTEGER, got -1",
+		},
+		{
+			name:     "too large shift count",
+			input:    `{"command": {"symbol": "<<", "args": [1, 9223372036854775807]}}`,
+			expected: "shift count of '<<' must not exceed 1048576, got 9223372036854775807",
+		},
+		{
+			name:     "too large exponent",
+			input:    `{"command": {"symbol": "pow", "args": [3, 100000000000]}}`,
+			expected: "result of 'pow' exceeds the limit of 1048576 bits: pow(3, 100000000000)",
+		},
+		{
+			name:     "float division by zero",
+			input:    `{"command": {"symbol": "/", "args": [1.5, 0.0]}}`,
+			expected: "division by zero",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	input := `
+		[
+			{
+				"command": {
+					"symbol": "random_seed",
+					"args": 42
+				}
+			},
+			{
+				"command": {
+					"symbol": "random_int",
+					"args": [1, 6]
+				}
+			},
+			{
+				"command": {
+					"symbol": "random"
+				}
+			},
+			{
+				"command": {
+					"symbol": "random",
+					"args": []
+				}
+			}
+		]`
+
+	first, ok := testEval(t, input).(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array")
+	}
+	second, ok := testEval(t, input).(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array")
+	}
+
+	if !object.Equal(first, second) {
+		t.Errorf("random results differ with the same seed. first=%s, second=%s", first.Inspect(), second.Inspect())
+	}
+
+	randomInt, ok := first.Elements[1].(*object.Integer)
+	if !ok || randomInt.Value < 1 || randomInt.Value > 6 {
+		t.Errorf("random_int out of range. got=%s", first.Elements[1].Inspect())
+	}
+	for _, el := range first.Elements[2:] {
+		randomFloat, ok := el.(*object.Float)
+		if !ok || randomFloat.Value < 0 || randomFloat.Value >= 1 {
+			t.Errorf("random out of range. got=%s", el.Inspect())
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/JunNishimura/jsop/object"
+)
+
+type OverflowMode int
+
+const (
+	// OverflowPromote promotes the result of integer arithmetic to BigInt when it overflows int64.
+	OverflowPromote OverflowMode = iota
+	// OverflowError makes integer arithmetic return an error when it overflows int64.
+	OverflowError
+)
+
+var overflowMode = OverflowPromote
+
+// SetOverflowMode changes how integer arithmetic handles overflow.
+func SetOverflowMode(mode OverflowMode) {
+	overflowMode = mode
+}
+
+func isInteger(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInt:
+		return true
+	default:
+		return false
+	}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInt:
+		return obj.Value
+	default:
+		return nil
+	}
+}
+
+// normalizeBigInt returns Integer if the value fits in int64, so that
+// the same integer always has the same representation.
+func normalizeBigInt(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInt{Value: value}
+}
+
+// evalIntegerArithmetic applies the operator to the integers.
+// the divisor of / and % must be checked not to be zero in advance.
+func evalIntegerArithmetic(operator string, left, right object.Object) object.Object {
+	leftInt, isLeftInt := left.(*object.Integer)
+	rightInt, isRightInt := right.(*object.Integer)
+	if isLeftInt && isRightInt {
+		if result, ok := int64Arithmetic(operator, leftInt.Value, rightInt.Value); ok {
+			return &object.Integer{Value: result}
+		}
+		if overflowMode == OverflowError {
+			return newError("integer overflow: %d %s %d", leftInt.Value, operator, rightInt.Value)
+		}
+	}
+
+	leftBig, rightBig := toBigInt(left), toBigInt(right)
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(leftBig, rightBig)
+	case "-":
+		result.Sub(leftBig, rightBig)
+	case "*":
+		result.Mul(leftBig, rightBig)
+	case "/":
+		result.Quo(leftBig, rightBig)
+	case "%":
+		result.Rem(leftBig, rightBig)
+	default:
+		return newError("unknown operator: %s", operator)
+	}
+
+	return normalizeBigInt(result)
+}
+
+// int64Arithmetic applies the operator and reports false if the result overflows int64.
+func int64Arithmetic(operator string, left, right int64) (int64, bool) {
+	switch operator {
+	case "+":
+		result := left + right
+		return result, (result > left) == (right > 0)
+	case "-":
+		result := left - right
+		return result, (result < left) == (right > 0)
+	case "*":
+		if left == 0 || right == 0 {
+			return 0, true
+		}
+		result := left * right
+		return result, result/right == left && !(left == -1 && right == math.MinInt64) && !(right == -1 && left == math.MinInt64)
+	case "/":
+		return left / right, !(left == math.MinInt64 && right == -1)
+	case "%":
+		if right == -1 {
+			return 0, true
+		}
+		return left % right, true
+	default:
+		return 0, false
+	}
+}
+
+func compareIntegers(left, right object.Object) int {
+	leftInt, isLeftInt := left.(*object.Integer)
+	rightInt, isRightInt := right.(*object.Integer)
+	if isLeftInt && isRightInt {
+		switch {
+		case leftInt.Value < rightInt.Value:
+			return -1
+		case leftInt.Value > rightInt.Value:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return toBigInt(left).Cmp(toBigInt(right))
+}
+
+func isNumber(obj object.Object) bool {
+	_, isFloat := obj.(*object.Float)
+	return isFloat || isInteger(obj)
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.Float:
+		return obj.Value
+	default:
+		return math.NaN()
+	}
+}
+
+// evalNumberArithmetic applies the operator to the numbers.
+// the result is float if either of the operands is float, otherwise integer.
+func evalNumberArithmetic(operator string, left, right object.Object) object.Object {
+	if isInteger(left) && isInteger(right) {
+		return evalIntegerArithmetic(operator, left, right)
+	}
+
+	leftFloat, rightFloat := toFloat(left), toFloat(right)
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftFloat + rightFloat}
+	case "-":
+		return &object.Float{Value: leftFloat - rightFloat}
+	case "*":
+		return &object.Float{Value: leftFloat * rightFloat}
+	case "/":
+		return &object.Float{Value: leftFloat / rightFloat}
+	case "%":
+		return &object.Float{Value: math.Mod(leftFloat, rightFloat)}
+	default:
+		return newError("unknown operator: %s", operator)
+	}
+}
+
+func compareNumbers(left, right object.Object) int {
+	if isInteger(left) && isInteger(right) {
+		return compareIntegers(left, right)
+	}
+
+	leftFloat, rightFloat := toFloat(left), toFloat(right)
+	switch {
+	case leftFloat < rightFloat:
+		return -1
+	case leftFloat > rightFloat:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isZeroNumber(obj object.Object) bool {
+	if isInteger(obj) {
+		return toBigInt(obj).Sign() == 0
+	}
+	return toFloat(obj) == 0
+}
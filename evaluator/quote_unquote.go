@@ -1,7 +1,9 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/JunNishimura/jsop/ast"
@@ -9,58 +11,284 @@ import (
 	"github.com/JunNishimura/jsop/token"
 )
 
+const splicePrefix = ",@"
+
 func quote(exp ast.Expression, env *object.Environment) object.Object {
-	unquotedExp := evalUnquote(exp, env)
+	unquotedExp, err := evalUnquote(exp, env)
+	if err != nil {
+		return newError("%s", err)
+	}
 	return &object.Quote{Expression: unquotedExp}
 }
 
-func evalUnquote(quoted ast.Expression, env *object.Environment) ast.Expression {
-	return ast.Modify(quoted, func(exp ast.Expression) ast.Expression {
-		if !isUnquote(exp) {
-			return exp
+// splicedExpressions is the result of unquote-splice,
+// which is flattened into the array or the object containing it.
+type splicedExpressions struct {
+	source   ast.Expression
+	elements []ast.Expression
+	pairs    []*ast.KeyValuePair
+	isObject bool
+}
+
+func (se *splicedExpressions) TokenLiteral() string { return se.source.TokenLiteral() }
+func (se *splicedExpressions) String() string       { return se.source.String() }
+
+func evalUnquote(quoted ast.Expression, env *object.Environment) (ast.Expression, error) {
+	if err := checkUnquoteKeys(quoted); err != nil {
+		return nil, err
+	}
+
+	var err error
+	setErr := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+
+	unquoted := ast.Modify(quoted, func(exp ast.Expression) ast.Expression {
+		if isUnquoteSplice(exp) {
+			spliced, spliceErr := evalUnquoteSplice(exp, env)
+			if spliceErr != nil {
+				setErr(spliceErr)
+				return exp
+			}
+			return spliced
+		}
+
+		if isUnquote(exp) {
+			var unquoted ast.Expression
+			var unquoteErr error
+			switch exp := exp.(type) {
+			case *ast.KeyValueObject:
+				unquoted, unquoteErr = evalUnquoteCommand(exp, env)
+			case *ast.StringLiteral:
+				unquoted, unquoteErr = evalUnquoteString(exp, env)
+			default:
+				return exp
+			}
+			if unquoteErr != nil {
+				setErr(unquoteErr)
+				return exp
+			}
+			return unquoted
 		}
 
 		switch exp := exp.(type) {
+		case *ast.Array:
+			flattened, flattenErr := flattenArraySplices(exp)
+			if flattenErr != nil {
+				setErr(flattenErr)
+				return exp
+			}
+			return flattened
 		case *ast.KeyValueObject:
-			return evalUnquoteCommand(exp, env)
-		case *ast.StringLiteral:
-			return evalUnquoteString(exp, env)
+			flattened, flattenErr := flattenObjectSplices(exp)
+			if flattenErr != nil {
+				setErr(flattenErr)
+				return exp
+			}
+			return flattened
 		}
 
 		return exp
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if spliced, ok := unquoted.(*splicedExpressions); ok {
+		return nil, fmt.Errorf("unquote-splice must be inside an array or an object: %s", spliced)
+	}
+
+	return unquoted, nil
 }
 
-func evalUnquoteCommand(kvObj *ast.KeyValueObject, env *object.Environment) ast.Expression {
+// checkUnquoteKeys reports an error if a key of an object is an unquote,
+// since keys must be strings and cannot be replaced with the unquoted values.
+func checkUnquoteKeys(quoted ast.Expression) error {
+	var err error
+	ast.Inspect(quoted, func(exp ast.Expression) bool {
+		kvObj, ok := exp.(*ast.KeyValueObject)
+		if !ok || err != nil {
+			return err == nil
+		}
+		for _, kv := range kvObj.KV {
+			if strings.HasPrefix(kv.Key.Value, splicePrefix) {
+				err = fmt.Errorf("unquote-splice is not allowed in object keys: %q. Please write it as a value like {\"...\": %q}", kv.Key.Token.Literal, kv.Key.Token.Literal)
+				return false
+			}
+			if strings.HasPrefix(kv.Key.Value, ",") {
+				err = fmt.Errorf("unquote is not allowed in object keys: %q", kv.Key.Token.Literal)
+				return false
+			}
+		}
+		return true
+	})
+	return err
+}
+
+func evalUnquoteCommand(kvObj *ast.KeyValueObject, env *object.Environment) (ast.Expression, error) {
 	cmdVal, ok := kvObj.KVPairs()["command"]
 	if !ok {
-		return kvObj
+		return kvObj, nil
 	}
 	cmdObj, ok := cmdVal.(*ast.KeyValueObject)
 	if !ok {
-		return kvObj
+		return kvObj, nil
 	}
 
 	argsVal, ok := cmdObj.KVPairs()["args"]
 	if !ok {
-		return kvObj
+		return kvObj, nil
 	}
 
 	unquoted := Eval(argsVal, env)
+	if errObj, ok := unquoted.(*object.Error); ok {
+		return nil, errors.New(errObj.Message)
+	}
 	return convertObjectToExpression(unquoted)
 }
 
-func evalUnquoteString(strLit *ast.StringLiteral, env *object.Environment) ast.Expression {
+func evalUnquoteString(strLit *ast.StringLiteral, env *object.Environment) (ast.Expression, error) {
 	if obj, isFound := env.Get(strLit.Value[1:]); isFound {
 		return convertObjectToExpression(obj)
 	}
-	return strLit
+	return strLit, nil
+}
+
+// evalUnquoteSplice evaluates ",@name" or the unquote-splice command into the expressions to be spliced.
+// arrays are spliced into arrays, and objects and maps are spliced into objects.
+func evalUnquoteSplice(exp ast.Expression, env *object.Environment) (ast.Expression, error) {
+	var value object.Object
+	switch exp := exp.(type) {
+	case *ast.StringLiteral:
+		name := strings.TrimPrefix(exp.Value, splicePrefix)
+		obj, ok := env.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("symbol not found in unquote-splice: %s", name)
+		}
+		value = obj
+	case *ast.KeyValueObject:
+		cmdObj := exp.KVPairs()["command"].(*ast.KeyValueObject)
+		argsVal, ok := cmdObj.KVPairs()["args"]
+		if !ok {
+			return nil, fmt.Errorf("args key not found in unquote-splice: %s", exp)
+		}
+		value = Eval(argsVal, env)
+		if errObj, ok := value.(*object.Error); ok {
+			return nil, errors.New(errObj.Message)
+		}
+	}
+
+	spliced := &splicedExpressions{source: exp}
+	switch value := value.(type) {
+	case *object.Quote:
+		switch quoted := value.Expression.(type) {
+		case *ast.Array:
+			spliced.elements = quoted.Elements
+		case *ast.KeyValueObject:
+			spliced.pairs = quoted.KV
+			spliced.isObject = true
+		default:
+			return nil, fmt.Errorf("unquote-splice expects ARRAY or OBJECT, got %s", quoted)
+		}
+	case *object.Array:
+		for _, el := range value.Elements {
+			elExp, err := convertObjectToExpression(el)
+			if err != nil {
+				return nil, err
+			}
+			spliced.elements = append(spliced.elements, elExp)
+		}
+	case *object.Map:
+		for _, key := range value.Keys {
+			valueExp, err := convertObjectToExpression(value.Pairs[key])
+			if err != nil {
+				return nil, err
+			}
+			spliced.pairs = append(spliced.pairs, &ast.KeyValuePair{Key: mapKeyLiteral(key), Value: valueExp})
+		}
+		spliced.isObject = true
+	default:
+		return nil, fmt.Errorf("unquote-splice expects ARRAY or OBJECT, got %s", value.Type())
+	}
+
+	return spliced, nil
+}
+
+func flattenArraySplices(array *ast.Array) (ast.Expression, error) {
+	if !slices.ContainsFunc(array.Elements, isSplicedExpressions) {
+		return array, nil
+	}
+
+	elements := make([]ast.Expression, 0, len(array.Elements))
+	// places are the indexes in the flattened elements, which keep the places of the comments
+	places := make([]int, len(array.Elements)+1)
+	for i, el := range array.Elements {
+		places[i] = len(elements)
+		spliced, ok := el.(*splicedExpressions)
+		if !ok {
+			elements = append(elements, el)
+			continue
+		}
+		if spliced.isObject {
+			return nil, fmt.Errorf("cannot splice object into array: %s", spliced)
+		}
+		elements = append(elements, spliced.elements...)
+	}
+	places[len(array.Elements)] = len(elements)
+
+	return &ast.Array{Token: array.Token, Elements: elements, Comments: placeComments(array.Comments, places)}, nil
+}
+
+// flattenObjectSplices replaces the key/value pair whose value is spliced with the spliced pairs.
+// the key of such a pair is conventionally "...".
+func flattenObjectSplices(kvObj *ast.KeyValueObject) (ast.Expression, error) {
+	if !slices.ContainsFunc(kvObj.KV, func(kv *ast.KeyValuePair) bool { return isSplicedExpressions(kv.Value) }) {
+		return kvObj, nil
+	}
+
+	kvs := make([]*ast.KeyValuePair, 0, len(kvObj.KV))
+	places := make([]int, len(kvObj.KV)+1)
+	for i, kv := range kvObj.KV {
+		places[i] = len(kvs)
+		spliced, ok := kv.Value.(*splicedExpressions)
+		if !ok {
+			kvs = append(kvs, kv)
+			continue
+		}
+		if !spliced.isObject {
+			return nil, fmt.Errorf("cannot splice array into object: %s", spliced)
+		}
+		kvs = append(kvs, spliced.pairs...)
+	}
+	places[len(kvObj.KV)] = len(kvs)
+
+	return &ast.KeyValueObject{Token: kvObj.Token, KV: kvs, Comments: placeComments(kvObj.Comments, places)}, nil
+}
+
+// placeComments moves the comments to the places, which are the new indexes of the items before them.
+func placeComments(comments []*ast.Comment, places []int) []*ast.Comment {
+	var placed []*ast.Comment
+	for _, comment := range comments {
+		placed = append(placed, &ast.Comment{
+			Token: comment.Token,
+			Value: comment.Value,
+			Index: places[min(comment.Index, len(places)-1)],
+		})
+	}
+	return placed
+}
+
+func isSplicedExpressions(exp ast.Expression) bool {
+	_, ok := exp.(*splicedExpressions)
+	return ok
 }
 
 func isUnquote(exp ast.Expression) bool {
 	switch exp := exp.(type) {
 	case *ast.KeyValueObject:
-		return isUnquoteCommand(exp)
+		return isCommand(exp, "unquote")
 	case *ast.StringLiteral:
 		return strings.HasPrefix(exp.Value, ",")
 	}
@@ -68,7 +296,19 @@ func isUnquote(exp ast.Expression) bool {
 	return false
 }
 
-func isUnquoteCommand(exp ast.Expression) bool {
+func isUnquoteSplice(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.KeyValueObject:
+		return isCommand(exp, "unquote-splice")
+	case *ast.StringLiteral:
+		return strings.HasPrefix(exp.Value, splicePrefix)
+	}
+
+	return false
+}
+
+// isCommand reports whether the expression is a command calling the symbol.
+func isCommand(exp ast.Expression, symbol string) bool {
 	kvObj, ok := exp.(*ast.KeyValueObject)
 	if !ok {
 		return false
@@ -92,42 +332,84 @@ func isUnquoteCommand(exp ast.Expression) bool {
 		return false
 	}
 
-	return symbolStr.Value == "unquote"
+	return symbolStr.Value == symbol
 }
 
-func convertObjectToExpression(obj object.Object) ast.Expression {
+// convertObjectToExpression converts the value into the expression which evaluates to it.
+// it reports an error for the values which cannot be written in a program, such as functions.
+func convertObjectToExpression(obj object.Object) (ast.Expression, error) {
 	switch obj := obj.(type) {
 	case *object.Integer:
 		t := token.Token{
 			Type:    token.INT,
 			Literal: fmt.Sprintf("%d", obj.Value),
 		}
-		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, nil
+	case *object.BigInt:
+		t := token.Token{
+			Type:    token.INT,
+			Literal: obj.Value.String(),
+		}
+		return &ast.BigIntegerLiteral{Token: t, Value: obj.Value}, nil
+	case *object.Float:
+		t := token.Token{
+			Type:    token.FLOAT,
+			Literal: obj.Inspect(),
+		}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, nil
 	case *object.Boolean:
 		if obj.Value {
 			return &ast.Boolean{
 				Token: token.Token{Type: token.TRUE, Literal: "true"},
 				Value: true,
-			}
+			}, nil
 		}
 		return &ast.Boolean{
 			Token: token.Token{Type: token.FALSE, Literal: "false"},
 			Value: false,
-		}
+		}, nil
 	case *object.String:
-		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value}, Value: obj.Value}
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value}, Value: obj.Value}, nil
 	case *object.Array:
 		elements := make([]ast.Expression, len(obj.Elements))
 		for i, el := range obj.Elements {
-			elements[i] = convertObjectToExpression(el)
+			elExp, err := convertObjectToExpression(el)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elExp
 		}
 		return &ast.Array{
 			Token:    token.Token{Type: token.LBRACKET, Literal: "["},
 			Elements: elements,
+		}, nil
+	case *object.Map:
+		// a map is converted into the map literal like {"map": {"key": value}}
+		pairs := make([]*ast.KeyValuePair, len(obj.Keys))
+		for i, key := range obj.Keys {
+			valueExp, err := convertObjectToExpression(obj.Pairs[key])
+			if err != nil {
+				return nil, err
+			}
+			pairs[i] = &ast.KeyValuePair{Key: mapKeyLiteral(key), Value: valueExp}
 		}
+		return &ast.KeyValueObject{
+			Token: token.Token{Type: token.LBRACE, Literal: "{"},
+			KV: []*ast.KeyValuePair{
+				{
+					Key:   mapKeyLiteral("map"),
+					Value: &ast.KeyValueObject{Token: token.Token{Type: token.LBRACE, Literal: "{"}, KV: pairs},
+				},
+			},
+		}, nil
 	case *object.Quote:
-		return obj.Expression
+		return obj.Expression, nil
 	default:
-		return nil
+		return nil, fmt.Errorf("cannot unquote %s", obj.Type())
 	}
 }
+
+// mapKeyLiteral makes the key of an object, whose value is lowercased like the keys parsed from the source.
+func mapKeyLiteral(key string) *ast.StringLiteral {
+	return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: key}, Value: strings.ToLower(key)}
+}
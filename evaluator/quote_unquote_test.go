@@ -206,6 +206,11 @@ func TestQuoteUnquote(t *testing.T) {
 				}`,
 			expected: "3",
 		},
+		{
+			name:     "unquote map",
+			input:    `{"command": {"symbol": "quote", "args": {"command": {"symbol": "unquote", "args": {"map": {"Key": 1}}}}}}`,
+			expected: `{"map": {"key": 1}}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -221,3 +226,237 @@ func TestQuoteUnquote(t *testing.T) {
 		})
 	}
 }
+
+func TestQuoteUnquoteSplice(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "splice array into array",
+			input: `
+				{
+					"command": {
+						"symbol": "quote",
+						"args": [
+							0,
+							{
+								"command": {
+									"symbol": "unquote-splice",
+									"args": [1, 2]
+								}
+							},
+							3
+						]
+					}
+				}`,
+			expected: "[0, 1, 2, 3]",
+		},
+		{
+			name: "splice empty array",
+			input: `
+				{
+					"command": {
+						"symbol": "quote",
+						"args": [
+							0,
+							{
+								"command": {
+									"symbol": "unquote-splice",
+									"args": []
+								}
+							}
+						]
+					}
+				}`,
+			expected: "[0]",
+		},
+		{
+			name: "splice variable",
+			input: `
+				[
+					{
+						"set": {
+							"var": "$xs",
+							"val": ["a", "b"]
+						}
+					},
+					{
+						"command": {
+							"symbol": "quote",
+							"args": ["x", ",@$xs"]
+						}
+					}
+				]`,
+			expected: `["x", "a", "b"]`,
+		},
+		{
+			name: "splice map into object",
+			input: `
+				{
+					"command": {
+						"symbol": "quote",
+						"args": {
+							"command": {
+								"symbol": "+",
+								"...": {
+									"command": {
+										"symbol": "unquote-splice",
+										"args": {
+											"map": {
+												"args": [1, 2]
+											}
+										}
+									}
+								}
+							}
+						}
+					}
+				}`,
+			expected: `{"command": {"symbol": "+", "args": [1, 2]}}`,
+		},
+		{
+			name: "splice keeps comments of array",
+			input: `
+				[
+					{"set": {"var": "$xs", "val": ["a", "b"]}},
+					{
+						"command": {
+							"symbol": "quote",
+							"args": [{"//": "generated"}, ",@$xs", {"//": "end of splice"}, 1]
+						}
+					}
+				]`,
+			expected: `[{"//": "generated"}, "a", "b", {"//": "end of splice"}, 1]`,
+		},
+		{
+			name: "splice keeps comments of object",
+			input: `
+				{
+					"command": {
+						"symbol": "quote",
+						"args": {
+							"command": {
+								"//": "generated",
+								"...": {"command": {"symbol": "unquote-splice", "args": {"map": {"symbol": "+"}}}},
+								"//": "end of splice",
+								"args": [1, 2]
+							}
+						}
+					}
+				}`,
+			expected: `{"command": {"//": "generated", "symbol": "+", "//": "end of splice", "args": [1, 2]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			if array, ok := evaluated.(*object.Array); ok {
+				evaluated = array.Elements[len(array.Elements)-1]
+			}
+			quote, ok := evaluated.(*object.Quote)
+			if !ok {
+				t.Fatalf("expected object.Quote. got=%T (%+v)", evaluated, evaluated)
+			}
+			if quote.Expression.String() != tt.expected {
+				t.Errorf("expected=%q. got=%q", tt.expected, quote.Expression.String())
+			}
+		})
+	}
+}
+
+func TestQuoteUnquoteSpliceError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "splice outside of array or object",
+			input: `
+				{
+					"command": {
+						"symbol": "quote",
+						"args": {
+							"command": {
+								"symbol": "unquote-splice",
+								"args": [1, 2]
+							}
+						}
+					}
+				}`,
+			expected: "unquote-splice must be inside an array or an object: {\"command\": {\"symbol\": \"unquote-splice\", \"args\": [1, 2]}}",
+		},
+		{
+			name: "splice non collection",
+			input: `
+				{
+					"command": {
+						"symbol": "quote",
+						"args": [
+							{
+								"command": {
+									"symbol": "unquote-splice",
+									"args": 1
+								}
+							}
+						]
+					}
+				}`,
+			expected: "unquote-splice expects ARRAY or OBJECT, got INTEGER",
+		},
+		{
+			name: "splice array into object",
+			input: `
+				{
+					"command": {
+						"symbol": "quote",
+						"args": {
+							"...": {
+								"command": {
+									"symbol": "unquote-splice",
+									"args": [1, 2]
+								}
+							}
+						}
+					}
+				}`,
+			expected: "cannot splice array into object: {\"command\": {\"symbol\": \"unquote-splice\", \"args\": [1, 2]}}",
+		},
+		{
+			name:     "unquote function",
+			input:    `{"command": {"symbol": "quote", "args": {"command": {"symbol": "unquote", "args": {"lambda": {"params": [], "body": 1}}}}}}`,
+			expected: "cannot unquote FUNCTION",
+		},
+		{
+			name:     "unquote error",
+			input:    `{"command": {"symbol": "quote", "args": {"command": {"symbol": "unquote", "args": "$undefined"}}}}`,
+			expected: "symbol not found: $undefined",
+		},
+		{
+			name:     "unquote in object key",
+			input:    `{"command": {"symbol": "quote", "args": {"command": {"symbol": "+", "args": {",k": 1}}}}}`,
+			expected: "unquote is not allowed in object keys: \",k\"",
+		},
+		{
+			name:     "unquote-splice in object key",
+			input:    `{"command": {"symbol": "quote", "args": {",@$x": 1}}}`,
+			expected: "unquote-splice is not allowed in object keys: \",@$x\". Please write it as a value like {\"...\": \",@$x\"}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
@@ -1,11 +1,25 @@
 package lexer
 
 import (
+	"encoding/json"
+	"math/big"
 	"strings"
 
 	"github.com/JunNishimura/jsop/token"
 )
 
+// Dialect is the flavor of JSON accepted by the lexer.
+type Dialect string
+
+const (
+	// JSON is the strict JSON, which is the default.
+	JSON Dialect = "json"
+	// JSONC accepts // and /* */ comments and trailing commas.
+	JSONC Dialect = "jsonc"
+	// JSON5 accepts single quoted strings, unquoted keys and hexadecimal integers in addition to JSONC.
+	JSON5 Dialect = "json5"
+)
+
 type StringReadState int
 
 const (
@@ -20,15 +34,37 @@ type Lexer struct {
 	nextPos   int
 	curChar   byte
 	strRState StringReadState
+	// quote is the quotation mark of the string being read
+	quote byte
+	// line and column of curChar
+	line    int
+	column  int
+	dialect Dialect
+	// pending are the tokens to be returned before reading the input, e.g. the quotes of an unquoted key
+	pending []token.Token
+	// prevType is the type of the last token returned, which tells whether a comma follows a value
+	prevType token.TokenType
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input, strRState: notString}
+	return NewWithDialect(input, JSON)
+}
+
+// NewWithDialect returns the lexer which accepts the extensions of the dialect.
+func NewWithDialect(input string, dialect Dialect) *Lexer {
+	l := &Lexer{input: input, strRState: notString, quote: '"', line: 1, dialect: dialect}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.curChar == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
 	if l.nextPos >= len(l.input) {
 		l.curChar = 0
 	} else {
@@ -39,17 +75,32 @@ func (l *Lexer) readChar() {
 }
 
 func (l *Lexer) NextToken() token.Token {
+	tok := l.nextToken()
+	l.prevType = tok.Type
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 
+	if len(l.pending) > 0 {
+		tok = l.pending[0]
+		l.pending = l.pending[1:]
+		return tok
+	}
+
 	if l.strRState == readStart {
 		l.strRState = readEnd
+		pos := l.position()
 		return token.Token{
 			Type:    token.STRING,
 			Literal: l.readQuotedString(),
+			Pos:     pos,
 		}
 	}
 
 	l.skipWhitespace()
+	pos := l.position()
 
 	switch l.curChar {
 	case '{':
@@ -61,16 +112,23 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = newToken(token.RBRACKET, l.curChar)
 	case '"':
-		switch l.strRState {
-		case notString:
-			l.strRState = readStart
-		case readEnd:
-			l.strRState = notString
+		tok = l.quoteToken()
+	case '\'':
+		if l.dialect != JSON5 {
+			tok = newToken(token.ILLEGAL, l.curChar)
+			break
 		}
-		tok = newToken(token.DOUBLE_QUOTE, l.curChar)
+		tok = l.quoteToken()
 	case ':':
 		tok = newToken(token.COLON, l.curChar)
 	case ',':
+		// the trailing comma is skipped as if it were whitespace, but only after a value, e.g. not in [,]
+		if l.allowsTrailingComma() && l.followsValue() {
+			if next := l.peekSignificant(l.nextPos); next == ']' || next == '}' {
+				l.readChar()
+				return l.nextToken()
+			}
+		}
 		tok = newToken(token.COMMA, l.curChar)
 	case '-':
 		tok = newToken(token.MINUS, l.curChar)
@@ -78,32 +136,165 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
+		if l.dialect == JSON5 && l.curChar == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X') {
+			tok = l.readHexNumber()
+			tok.Pos = pos
+			return tok
+		}
+		if l.dialect == JSON5 && l.isUnquotedKey() {
+			key := l.readString(isIdentifierPart)
+			l.pending = append(l.pending,
+				token.Token{Type: token.STRING, Literal: key, Pos: pos},
+				token.Token{Type: token.DOUBLE_QUOTE, Literal: "\"", Pos: pos},
+			)
+			return token.Token{Type: token.DOUBLE_QUOTE, Literal: "\"", Pos: pos}
+		}
+
 		if isDigit(l.curChar) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else if isLetter(l.curChar) {
 			strLiteral := l.readString(isLetter)
 			trimmedStr := strings.TrimSpace(strLiteral)
 			if trimmedStr == "true" {
-				return token.Token{Type: token.TRUE, Literal: trimmedStr}
+				return token.Token{Type: token.TRUE, Literal: trimmedStr, Pos: pos}
 			} else if trimmedStr == "false" {
-				return token.Token{Type: token.FALSE, Literal: trimmedStr}
+				return token.Token{Type: token.FALSE, Literal: trimmedStr, Pos: pos}
 			}
 
-			return token.Token{Type: token.STRING, Literal: strLiteral}
+			return token.Token{Type: token.STRING, Literal: strLiteral, Pos: pos}
 		}
 		tok = newToken(token.ILLEGAL, l.curChar)
 	}
+	tok.Pos = pos
 
 	l.readChar()
 	return tok
 }
 
+func (l *Lexer) position() token.Position {
+	return token.Position{Line: l.line, Column: l.column}
+}
+
+// quoteToken returns the token of the quotation mark, which opens or closes a string.
+func (l *Lexer) quoteToken() token.Token {
+	switch l.strRState {
+	case notString:
+		l.strRState = readStart
+		l.quote = l.curChar
+	case readEnd:
+		l.strRState = notString
+	}
+	return newToken(token.DOUBLE_QUOTE, l.curChar)
+}
+
+func (l *Lexer) allowsComments() bool {
+	return l.dialect == JSONC || l.dialect == JSON5
+}
+
+func (l *Lexer) allowsTrailingComma() bool {
+	return l.dialect == JSONC || l.dialect == JSON5
+}
+
+// followsValue reports whether the last token ends a value, after which a trailing comma may be written.
+func (l *Lexer) followsValue() bool {
+	switch l.prevType {
+	case "", token.LBRACKET, token.LBRACE, token.COMMA, token.COLON:
+		return false
+	}
+	return true
+}
+
+// skipWhitespace skips whitespace, and comments as well if the dialect allows them.
 func (l *Lexer) skipWhitespace() {
-	for isWhitespace(l.curChar) {
+	for {
+		for isWhitespace(l.curChar) {
+			l.readChar()
+		}
+		if !l.allowsComments() || l.curChar != '/' {
+			return
+		}
+
+		switch l.peekChar() {
+		case '/':
+			for l.curChar != '\n' && l.curChar != 0 {
+				l.readChar()
+			}
+		case '*':
+			l.readChar()
+			l.readChar()
+			for !(l.curChar == '*' && l.peekChar() == '/') && l.curChar != 0 {
+				l.readChar()
+			}
+			// skip the closing */ unless the comment is not closed
+			if l.curChar != 0 {
+				l.readChar()
+				l.readChar()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peekSignificant returns the first character from the index which is neither whitespace nor a comment,
+// without moving the lexer. it returns 0 at the end of the input.
+func (l *Lexer) peekSignificant(index int) byte {
+	for index < len(l.input) {
+		ch := l.input[index]
+		switch {
+		case isWhitespace(ch):
+			index++
+		case l.allowsComments() && strings.HasPrefix(l.input[index:], "//"):
+			newline := strings.IndexByte(l.input[index:], '\n')
+			if newline < 0 {
+				return 0
+			}
+			index += newline
+		case l.allowsComments() && strings.HasPrefix(l.input[index:], "/*"):
+			end := strings.Index(l.input[index+2:], "*/")
+			if end < 0 {
+				return 0
+			}
+			index += 2 + end + 2
+		default:
+			return ch
+		}
+	}
+	return 0
+}
+
+// isUnquotedKey reports whether an identifier followed by a colon starts at the current character.
+func (l *Lexer) isUnquotedKey() bool {
+	if !isIdentifierStart(l.curChar) {
+		return false
+	}
+
+	end := l.curPos
+	for end < len(l.input) && isIdentifierPart(l.input[end]) {
+		end++
+	}
+	return l.peekSignificant(end) == ':'
+}
+
+// readHexNumber reads a hexadecimal integer like 0x1F.
+// the literal is converted to decimal so that the program can be written back as JSON.
+func (l *Lexer) readHexNumber() token.Token {
+	startPos := l.curPos
+	// skip 0x
+	l.readChar()
+	l.readChar()
+	hexPos := l.curPos
+	for isHexDigit(l.curChar) {
 		l.readChar()
 	}
+
+	value, ok := new(big.Int).SetString(l.input[hexPos:l.curPos], 16)
+	if !ok {
+		return token.Token{Type: token.ILLEGAL, Literal: l.input[startPos:l.curPos]}
+	}
+	return token.Token{Type: token.INT, Literal: value.String()}
 }
 
 func newToken(tokenType token.TokenType, ch byte) token.Token {
@@ -122,12 +313,56 @@ func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
 }
 
-func (l *Lexer) readNumber() string {
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+// isIdentifierStart reports whether the character can start an unquoted key, e.g. $x and _name.
+func isIdentifierStart(ch byte) bool {
+	return isLetter(ch) || ch == '_' || ch == '$'
+}
+
+func isIdentifierPart(ch byte) bool {
+	return isIdentifierStart(ch) || isDigit(ch)
+}
+
+// readNumber reads an integer or a number with fraction and/or exponent parts like 1.5e-3.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	startPos := l.curPos
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+	if l.curChar == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if l.curChar == 'e' || l.curChar == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && l.nextPos+1 < len(l.input) && isDigit(l.input[l.nextPos+1])) {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.curChar == '+' || l.curChar == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+
+	return tokenType, l.input[startPos:l.curPos]
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.curChar) {
 		l.readChar()
 	}
-	return l.input[startPos:l.curPos]
+}
+
+func (l *Lexer) peekChar() byte {
+	if l.nextPos >= len(l.input) {
+		return 0
+	}
+	return l.input[l.nextPos]
 }
 
 func (l *Lexer) readString(filters ...func(byte) bool) string {
@@ -145,12 +380,58 @@ func (l *Lexer) readString(filters ...func(byte) bool) string {
 	return l.input[startPos:l.curPos]
 }
 
+// readQuotedString reads the string until the closing quote,
+// and decodes the escape sequences like \" and \n in the same way as JSON.
 func (l *Lexer) readQuotedString() string {
 	startPos := l.curPos
+	hasEscape := false
 
-	for l.curChar != '"' && l.curChar != 0 {
+	for l.curChar != l.quote && l.curChar != 0 {
+		if l.curChar == '\\' {
+			hasEscape = true
+			l.readChar()
+			if l.curChar == 0 {
+				break
+			}
+		}
 		l.readChar()
 	}
 
-	return l.input[startPos:l.curPos]
+	rawStr := l.input[startPos:l.curPos]
+	if !hasEscape {
+		return rawStr
+	}
+
+	if l.quote == '\'' {
+		rawStr = singleQuotedToJSON(rawStr)
+	}
+
+	var decoded string
+	if err := json.Unmarshal([]byte(`"`+rawStr+`"`), &decoded); err != nil {
+		return rawStr
+	}
+	return decoded
+}
+
+// singleQuotedToJSON converts the content of a single quoted string into that of a double quoted one,
+// that is, \' is unescaped and " is escaped.
+func singleQuotedToJSON(rawStr string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(rawStr); i++ {
+		switch {
+		case rawStr[i] == '\\' && i+1 < len(rawStr):
+			if rawStr[i+1] != '\'' {
+				out.WriteByte('\\')
+			}
+			out.WriteByte(rawStr[i+1])
+			i++
+		case rawStr[i] == '"':
+			out.WriteString(`\"`)
+		default:
+			out.WriteByte(rawStr[i])
+		}
+	}
+
+	return out.String()
 }
@@ -37,6 +37,33 @@ func TestSingleProgram(t *testing.T) {
 				{Type: token.EOF, Literal: ""},
 			},
 		},
+		{
+			name:  "float",
+			input: "1.25",
+			expected: []token.Token{
+				{Type: token.FLOAT, Literal: "1.25"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "float with exponent",
+			input: "-2.5e-3",
+			expected: []token.Token{
+				{Type: token.MINUS, Literal: "-"},
+				{Type: token.FLOAT, Literal: "2.5e-3"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "string literal with escape sequences",
+			input: `"say \"hi\"\n"`,
+			expected: []token.Token{
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.STRING, Literal: "say \"hi\"\n"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
 		{
 			name:  "true",
 			input: "true",
@@ -1099,3 +1126,187 @@ func TestMultiplePrograms(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := `{
+  "a": [1, -2.5]
+}`
+
+	expected := []token.Position{
+		{Line: 1, Column: 1},
+		{Line: 2, Column: 3},
+		{Line: 2, Column: 4},
+		{Line: 2, Column: 5},
+		{Line: 2, Column: 6},
+		{Line: 2, Column: 8},
+		{Line: 2, Column: 9},
+		{Line: 2, Column: 10},
+		{Line: 2, Column: 12},
+		{Line: 2, Column: 13},
+		{Line: 2, Column: 16},
+		{Line: 3, Column: 1},
+		{Line: 3, Column: 2},
+	}
+
+	l := New(input)
+	for i, expectedPos := range expected {
+		tok := l.NextToken()
+		if tok.Pos != expectedPos {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%+v, got=%+v", i, tok.Literal, expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dialect  Dialect
+		expected []token.Token
+	}{
+		{
+			name:    "comments in jsonc",
+			dialect: JSONC,
+			input: `// line comment
+				[1, /* block
+				comment */ "//"]`,
+			expected: []token.Token{
+				{Type: token.LBRACKET, Literal: "["},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.COMMA, Literal: ","},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.STRING, Literal: "//"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.RBRACKET, Literal: "]"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:    "trailing commas in jsonc",
+			dialect: JSONC,
+			input:   `[1, {"a": 2,}, /* last */ ]`,
+			expected: []token.Token{
+				{Type: token.LBRACKET, Literal: "["},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.COMMA, Literal: ","},
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.STRING, Literal: "a"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.COLON, Literal: ":"},
+				{Type: token.INT, Literal: "2"},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.RBRACKET, Literal: "]"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:    "comma without value in array is not skipped",
+			dialect: JSONC,
+			input:   `[,]`,
+			expected: []token.Token{
+				{Type: token.LBRACKET, Literal: "["},
+				{Type: token.COMMA, Literal: ","},
+				{Type: token.RBRACKET, Literal: "]"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:    "comma without value in object is not skipped",
+			dialect: JSON5,
+			input:   `{"map": {,}}`,
+			expected: []token.Token{
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.STRING, Literal: "map"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.COLON, Literal: ":"},
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.COMMA, Literal: ","},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:    "single quoted string in json5",
+			dialect: JSON5,
+			input:   `'it\'s "quoted"'`,
+			expected: []token.Token{
+				{Type: token.DOUBLE_QUOTE, Literal: "'"},
+				{Type: token.STRING, Literal: `it's "quoted"`},
+				{Type: token.DOUBLE_QUOTE, Literal: "'"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:    "unquoted keys in json5",
+			dialect: JSON5,
+			input:   `{set: {$x : 1}}`,
+			expected: []token.Token{
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.STRING, Literal: "set"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.COLON, Literal: ":"},
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.STRING, Literal: "$x"},
+				{Type: token.DOUBLE_QUOTE, Literal: "\""},
+				{Type: token.COLON, Literal: ":"},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:    "hexadecimal integer in json5",
+			dialect: JSON5,
+			input:   `[0x1F, -0xff]`,
+			expected: []token.Token{
+				{Type: token.LBRACKET, Literal: "["},
+				{Type: token.INT, Literal: "31"},
+				{Type: token.COMMA, Literal: ","},
+				{Type: token.MINUS, Literal: "-"},
+				{Type: token.INT, Literal: "255"},
+				{Type: token.RBRACKET, Literal: "]"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			name:    "comments are illegal in json",
+			dialect: JSON,
+			input:   `[1, // comment`,
+			expected: []token.Token{
+				{Type: token.LBRACKET, Literal: "["},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.COMMA, Literal: ","},
+				{Type: token.ILLEGAL, Literal: "/"},
+			},
+		},
+		{
+			name:    "single quotes are illegal in jsonc",
+			dialect: JSONC,
+			input:   `'a'`,
+			expected: []token.Token{
+				{Type: token.ILLEGAL, Literal: "'"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewWithDialect(tt.input, tt.dialect)
+			for i, expected := range tt.expected {
+				tok := l.NextToken()
+				if tok.Type != expected.Type {
+					t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected.Type, tok.Type)
+				}
+				if tok.Literal != expected.Literal {
+					t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, expected.Literal, tok.Literal)
+				}
+			}
+		})
+	}
+}
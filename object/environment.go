@@ -48,3 +48,10 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// Define creates the variable in the current environment even if
+// the variable with the same name exists in the outer environment.
+func (e *Environment) Define(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
@@ -0,0 +1,96 @@
+package object
+
+import (
+	"math"
+	"math/big"
+)
+
+// Equal reports whether two objects have the same type and the same value.
+// numbers are compared by their values regardless of their types, so 1 equals 1.0.
+// arrays and maps are compared recursively, and the order of keys of maps does not matter.
+// functions, builtin functions and macros are equal only to themselves.
+func Equal(left, right Object) bool {
+	if isNumber(left) && isNumber(right) {
+		return numberEqual(left, right)
+	}
+	if left.Type() != right.Type() {
+		return false
+	}
+
+	switch left := left.(type) {
+	case *String:
+		return left.Value == right.(*String).Value
+	case *Boolean:
+		return left.Value == right.(*Boolean).Value
+	case *Null:
+		return true
+	case *Array:
+		rightArray := right.(*Array)
+		if len(left.Elements) != len(rightArray.Elements) {
+			return false
+		}
+		for i := range left.Elements {
+			if !Equal(left.Elements[i], rightArray.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Map:
+		rightMap := right.(*Map)
+		if len(left.Keys) != len(rightMap.Keys) {
+			return false
+		}
+		for _, key := range left.Keys {
+			rightValue, ok := rightMap.Get(key)
+			if !ok || !Equal(left.Pairs[key], rightValue) {
+				return false
+			}
+		}
+		return true
+	case *Quote:
+		return left.Expression.String() == right.(*Quote).Expression.String()
+	case *Error:
+		return left.Message == right.(*Error).Message
+	default:
+		return left == right
+	}
+}
+
+func isNumber(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt, *Float:
+		return true
+	default:
+		return false
+	}
+}
+
+// numberEqual compares the numbers exactly, without rounding integers to float64.
+func numberEqual(left, right Object) bool {
+	leftFloat, leftIsFloat := left.(*Float)
+	rightFloat, rightIsFloat := right.(*Float)
+	switch {
+	case leftIsFloat && rightIsFloat:
+		return leftFloat.Value == rightFloat.Value
+	case leftIsFloat:
+		return floatEqualsInt(leftFloat.Value, numberBigInt(right))
+	case rightIsFloat:
+		return floatEqualsInt(rightFloat.Value, numberBigInt(left))
+	default:
+		return numberBigInt(left).Cmp(numberBigInt(right)) == 0
+	}
+}
+
+func floatEqualsInt(f float64, i *big.Int) bool {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return false
+	}
+	return big.NewFloat(f).Cmp(new(big.Float).SetInt(i)) == 0
+}
+
+func numberBigInt(obj Object) *big.Int {
+	if integer, ok := obj.(*Integer); ok {
+		return big.NewInt(integer.Value)
+	}
+	return obj.(*BigInt).Value
+}
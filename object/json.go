@@ -0,0 +1,82 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/JunNishimura/jsop/ast"
+)
+
+// ToJSON serializes the object as JSON. keys of maps keep their order unless sortKeys is true,
+// and the output is indented by indent when it is not empty.
+// functions, builtin functions, macros and quotes are serialized as strings of their Inspect.
+func ToJSON(obj Object, indent string, sortKeys bool) (string, error) {
+	var out bytes.Buffer
+	if err := writeJSON(&out, obj, sortKeys); err != nil {
+		return "", err
+	}
+
+	if indent == "" {
+		return out.String(), nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", indent); err != nil {
+		return "", err
+	}
+	return indented.String(), nil
+}
+
+func writeJSON(out *bytes.Buffer, obj Object, sortKeys bool) error {
+	switch obj := obj.(type) {
+	case *Integer, *BigInt, *Boolean, *Null:
+		out.WriteString(obj.Inspect())
+	case *Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return fmt.Errorf("cannot serialize %s to JSON", obj.Inspect())
+		}
+		out.WriteString(obj.Inspect())
+	case *String:
+		out.WriteString(ast.QuoteJSONString(obj.Value))
+	case *Array:
+		out.WriteString("[")
+		for i, el := range obj.Elements {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			if err := writeJSON(out, el, sortKeys); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	case *Map:
+		keys := obj.Keys
+		if sortKeys {
+			keys = make([]string, len(obj.Keys))
+			copy(keys, obj.Keys)
+			sort.Strings(keys)
+		}
+
+		out.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			out.WriteString(ast.QuoteJSONString(key))
+			out.WriteString(":")
+			if err := writeJSON(out, obj.Pairs[key], sortKeys); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	case *ReturnValue:
+		return writeJSON(out, obj.Value, sortKeys)
+	default:
+		out.WriteString(ast.QuoteJSONString(obj.Inspect()))
+	}
+
+	return nil
+}
@@ -3,16 +3,23 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
 
 	"github.com/JunNishimura/jsop/ast"
+	"github.com/JunNishimura/jsop/token"
 )
 
 const (
 	ERROR_OBJ        = "ERROR"
 	INTEGER_OBJ      = "INTEGER"
+	BIGINT_OBJ       = "BIGINT"
+	FLOAT_OBJ        = "FLOAT"
 	STRING_OBJ       = "STRING"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	ARRAY_OBJ        = "ARRAY"
+	MAP_OBJ          = "MAP"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	BREAK_OBJ        = "BREAK"
@@ -37,6 +44,28 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+// BigInt is an integer which does not fit in int64.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (bi *BigInt) Type() ObjectType { return BIGINT_OBJ }
+func (bi *BigInt) Inspect() string  { return bi.Value.String() }
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	// keep the decimal point so that it is distinguishable from integer
+	if !strings.ContainsAny(str, ".eIN") {
+		str += ".0"
+	}
+	return str
+}
+
 type String struct {
 	Value string
 }
@@ -64,13 +93,60 @@ func (a *Array) Inspect() string {
 		if i > 0 {
 			out.WriteString(", ")
 		}
-		out.WriteString(el.Inspect())
+		out.WriteString(inspectElement(el))
 	}
 	out.WriteString("]")
 
 	return out.String()
 }
 
+type Map struct {
+	Keys  []string
+	Pairs map[string]Object
+}
+
+func NewMap() *Map {
+	return &Map{Keys: []string{}, Pairs: make(map[string]Object)}
+}
+
+func (m *Map) Type() ObjectType { return MAP_OBJ }
+func (m *Map) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+	for i, key := range m.Keys {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(ast.QuoteJSONString(key))
+		out.WriteString(": ")
+		out.WriteString(inspectElement(m.Pairs[key]))
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (m *Map) Get(key string) (Object, bool) {
+	value, ok := m.Pairs[key]
+	return value, ok
+}
+
+func (m *Map) Set(key string, value Object) {
+	if _, ok := m.Pairs[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Pairs[key] = value
+}
+
+// inspectElement quotes strings so that elements of arrays and maps are not ambiguous.
+func inspectElement(obj Object) string {
+	if str, ok := obj.(*String); ok {
+		return ast.QuoteJSONString(str.Value)
+	}
+	return obj.Inspect()
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType { return NULL_OBJ }
@@ -95,13 +171,15 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
 	Message string
+	// Pos is the position of the innermost expression which caused the error. Line is 0 when it is unknown.
+	Pos token.Position
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
 type Function struct {
-	Parameters []*ast.StringLiteral
+	Parameters []ast.Expression
 	Body       ast.Expression
 	Env        *Environment
 }
@@ -115,7 +193,11 @@ func (f *Function) Inspect() string {
 		if i > 0 {
 			out.WriteString(", ")
 		}
-		out.WriteString(p.Value)
+		if symbol, ok := p.(*ast.StringLiteral); ok {
+			out.WriteString(symbol.Value)
+		} else {
+			out.WriteString(p.String())
+		}
 	}
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
@@ -128,6 +210,11 @@ type BuiltinFunction func(args Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
+	// Params are the names of parameters used to call the builtin function with named arguments.
+	// a builtin function with a single parameter receives the argument as it is, otherwise as an array.
+	Params []string
+	// OptionalParams is the number of trailing Params which can be omitted in named arguments.
+	OptionalParams int
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -142,8 +229,14 @@ func (q *Quote) Inspect() string  { return q.Expression.String() }
 
 type Macro struct {
 	Keys []*ast.StringLiteral
-	Body ast.Expression
-	Env  *Environment
+	// Defaults are the default values of the optional keys
+	Defaults map[string]ast.Expression
+	// RestKey collects the keys which are not declared in Keys if it is not empty
+	RestKey string
+	Body    ast.Expression
+	Env     *Environment
+	// Hygienic makes the variables bound in the expansion renamed not to conflict with the caller's
+	Hygienic bool
 }
 
 func (m *Macro) Type() ObjectType { return MACRO_OBJ }
@@ -156,6 +249,15 @@ func (m *Macro) Inspect() string {
 			out.WriteString(", ")
 		}
 		out.WriteString(k.Value)
+		if _, ok := m.Defaults[k.Value]; ok {
+			out.WriteString("?")
+		}
+	}
+	if m.RestKey != "" {
+		if len(m.Keys) > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString("..." + m.RestKey)
 	}
 	out.WriteString(") {\n")
 	out.WriteString(m.Body.String())
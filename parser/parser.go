@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -33,6 +35,16 @@ func (p *Parser) nextToken() {
 	p.peekToken = p.l.NextToken()
 }
 
+// ParseError is an error of parsing with the position of the token where the parser stopped.
+type ParseError struct {
+	Message string
+	Pos     token.Position
+}
+
+func (pe *ParseError) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", pe.Message, pe.Pos.Line, pe.Pos.Column)
+}
+
 func (p *Parser) ParseProgram() (ast.Expression, error) {
 	if p.curTokenIs(token.EOF) {
 		return nil, nil
@@ -40,16 +52,22 @@ func (p *Parser) ParseProgram() (ast.Expression, error) {
 
 	exp, err := p.parseExpression()
 	if err != nil {
-		return nil, err
+		return nil, p.newParseError(err)
 	}
 
 	if err := p.expectCurToken(token.EOF); err != nil {
-		return nil, err
+		return nil, p.newParseError(err)
 	}
 
 	return exp, nil
 }
 
+// newParseError attaches the position of the current token to the error,
+// since the parser does not advance any more after an error occurs.
+func (p *Parser) newParseError(err error) *ParseError {
+	return &ParseError{Message: err.Error(), Pos: p.curToken.Pos}
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -109,7 +127,15 @@ func (p *Parser) parseObject() (ast.Object, error) {
 		if err != nil {
 			return nil, err
 		}
-		object.KV = append(object.KV, kvPair)
+		if kvPair.Key.Value == ast.CommentKey {
+			object.Comments = append(object.Comments, &ast.Comment{
+				Token: kvPair.Key.Token,
+				Value: kvPair.Value,
+				Index: len(object.KV),
+			})
+		} else {
+			object.KV = append(object.KV, kvPair)
+		}
 
 		if !p.curTokenIs(token.COMMA) {
 			break
@@ -160,6 +186,8 @@ func (p *Parser) parseAtom() (ast.Expression, error) {
 		return p.parsePrefixAtom()
 	case token.INT:
 		return p.parseIntegerLiteral()
+	case token.FLOAT:
+		return p.parseFloatLiteral()
 	case token.TRUE, token.FALSE:
 		return p.parseBoolean()
 	case token.DOUBLE_QUOTE:
@@ -167,9 +195,7 @@ func (p *Parser) parseAtom() (ast.Expression, error) {
 	case token.LBRACKET:
 		return p.parseArray()
 	default:
-		err := fmt.Errorf("unexpected token type %s", p.curToken.Type)
-		p.nextToken()
-		return nil, err
+		return nil, fmt.Errorf("unexpected token type %s", p.curToken.Type)
 	}
 }
 
@@ -190,17 +216,41 @@ func (p *Parser) parsePrefixAtom() (*ast.PrefixAtom, error) {
 	return pa, nil
 }
 
-func (p *Parser) parseIntegerLiteral() (*ast.IntegerLiteral, error) {
+func (p *Parser) parseIntegerLiteral() (ast.Expression, error) {
 	if !p.curTokenIs(token.INT) {
 		return nil, fmt.Errorf("expected integer, got %s instead", p.curToken.Type)
 	}
 
+	var result ast.Expression
 	intValue, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
+	if errors.Is(err, strconv.ErrRange) {
+		bigValue, ok := new(big.Int).SetString(p.curToken.Literal, 0)
+		if !ok {
+			return nil, fmt.Errorf("could not parse %q as integer", p.curToken.Literal)
+		}
+		result = &ast.BigIntegerLiteral{Token: p.curToken, Value: bigValue}
+	} else if err != nil {
 		return nil, fmt.Errorf("could not parse %q as integer", p.curToken.Literal)
+	} else {
+		result = &ast.IntegerLiteral{Token: p.curToken, Value: intValue}
+	}
+
+	p.nextToken()
+
+	return result, nil
+}
+
+func (p *Parser) parseFloatLiteral() (*ast.FloatLiteral, error) {
+	if !p.curTokenIs(token.FLOAT) {
+		return nil, fmt.Errorf("expected float, got %s instead", p.curToken.Type)
+	}
+
+	floatValue, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %q as float", p.curToken.Literal)
 	}
 
-	result := &ast.IntegerLiteral{Token: p.curToken, Value: intValue}
+	result := &ast.FloatLiteral{Token: p.curToken, Value: floatValue}
 
 	p.nextToken()
 
@@ -263,7 +313,15 @@ func (p *Parser) parseArray() (*ast.Array, error) {
 		if err != nil {
 			return nil, err
 		}
-		array.Elements = append(array.Elements, element)
+		// an object of only comments is not an element
+		if kvObj, ok := element.(*ast.KeyValueObject); ok && kvObj.IsComment() {
+			for _, comment := range kvObj.Comments {
+				comment.Index = len(array.Elements)
+				array.Comments = append(array.Comments, comment)
+			}
+		} else {
+			array.Elements = append(array.Elements, element)
+		}
 
 		if !p.curTokenIs(token.COMMA) {
 			break
@@ -47,6 +47,65 @@ func TestIntegerAtom(t *testing.T) {
 	}
 }
 
+func TestBigIntegerAtom(t *testing.T) {
+	input := "123456789012345678901234567890"
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("ParseProgram() error: %v", err)
+	}
+
+	bigIntAtom, ok := program.(*ast.BigIntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.BigIntegerLiteral. got=%T", program)
+	}
+	if bigIntAtom.Value.String() != input {
+		t.Fatalf("bigIntAtom.Value not %s. got=%s", input, bigIntAtom.Value.String())
+	}
+}
+
+func TestFloatAtom(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected float64
+	}{
+		{
+			name:     "float with fraction",
+			input:    "3.14",
+			expected: 3.14,
+		},
+		{
+			name:     "float with exponent",
+			input:    "1e3",
+			expected: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(tt.input)
+			p := New(l)
+
+			program, err := p.ParseProgram()
+			if err != nil {
+				t.Fatalf("ParseProgram() error: %v", err)
+			}
+
+			floatAtom, ok := program.(*ast.FloatLiteral)
+			if !ok {
+				t.Fatalf("exp not *ast.FloatLiteral. got=%T", program)
+			}
+			if floatAtom.Value != tt.expected {
+				t.Fatalf("floatAtom.Value not %f. got=%f", tt.expected, floatAtom.Value)
+			}
+		})
+	}
+}
+
 func TestStringAtom(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -2276,3 +2335,78 @@ func TestPrograms(t *testing.T) {
 		})
 	}
 }
+
+func TestParseErrorPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected token.Position
+	}{
+		{
+			name:     "trailing comma in array",
+			input:    "[1, 2,]",
+			expected: token.Position{Line: 1, Column: 7},
+		},
+		{
+			name: "missing colon in object",
+			input: `{
+  "set" {}
+}`,
+			expected: token.Position{Line: 2, Column: 9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(tt.input)
+			p := New(l)
+
+			_, err := p.ParseProgram()
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("err is not *ParseError. got=%T (%v)", err, err)
+			}
+			if parseErr.Pos != tt.expected {
+				t.Fatalf("wrong position. expected=%+v, got=%+v", tt.expected, parseErr.Pos)
+			}
+		})
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `[{"//": "head"}, {"set": {"var": "$x", "val": 1}, "//": "set x"}, 2, {"//": "tail"}]`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("ParseProgram() error: %v", err)
+	}
+
+	array, ok := program.(*ast.Array)
+	if !ok {
+		t.Fatalf("exp not *ast.Array. got=%T", program)
+	}
+	if len(array.Elements) != 2 {
+		t.Fatalf("array has wrong number of elements. expected=2, got=%d", len(array.Elements))
+	}
+	if len(array.Comments) != 2 || array.Comments[0].Index != 0 || array.Comments[1].Index != 2 {
+		t.Fatalf("array has wrong comments. got=%+v", array.Comments)
+	}
+
+	kvObject, ok := array.Elements[0].(*ast.KeyValueObject)
+	if !ok {
+		t.Fatalf("element not *ast.KeyValueObject. got=%T", array.Elements[0])
+	}
+	if len(kvObject.KV) != 1 {
+		t.Fatalf("object has wrong number of pairs. expected=1, got=%d", len(kvObject.KV))
+	}
+	if len(kvObject.Comments) != 1 || kvObject.Comments[0].Index != 1 || kvObject.Comments[0].Value.String() != `"set x"` {
+		t.Fatalf("object has wrong comments. got=%+v", kvObject.Comments)
+	}
+
+	if program.String() != input {
+		t.Fatalf("program.String() not %q. got=%q", input, program.String())
+	}
+}
@@ -5,6 +5,13 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
+}
+
+// Position is the location of the token in the source. both line and column start from 1.
+type Position struct {
+	Line   int
+	Column int
 }
 
 const (
@@ -12,6 +19,7 @@ const (
 	EOF     = "EOF"
 
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
 	MINUS = "-"